@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// runCheck reports overlapping subnets across plan files and fails if the
+// roots of any two plans overlap.
+func runCheck(args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	plans := make([]*subnet.Workspace, len(args))
+	for i, file := range args {
+		ws, err := subnet.LoadWorkspace(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		plans[i] = ws
+	}
+
+	conflicts := 0
+	for i := range plans {
+		for j := i + 1; j < len(plans); j++ {
+			for _, a := range plans[i].Roots {
+				for _, b := range plans[j].Roots {
+					if !subnet.Overlapping(a, b) {
+						continue
+					}
+					conflicts++
+					fmt.Printf("%s %s overlaps %s %s\n", args[i], describeSubnet(a), args[j], describeSubnet(b))
+					for _, o := range subnet.Overlaps(a, b) {
+						fmt.Printf("  %s overlaps %s\n", describeSubnet(o.A), describeSubnet(o.B))
+					}
+				}
+			}
+		}
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d overlapping pairs of roots", conflicts)
+	}
+	return nil
+}
+
+// describeSubnet returns a node's CIDR followed by its labels and metadata.
+func describeSubnet(n *subnet.Subnet) string {
+	return joinFields(n.CIDR(), attributes(n.Labels, n.Metadata))
+}
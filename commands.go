@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/store"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// command is a non-interactive subcommand such as "subnets convert".
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+// errUsage is returned by a command whose arguments are wrong; the caller
+// prints the command's usage line.
+var errUsage = errors.New("invalid arguments")
+
+var commands = map[string]command{
+	"check": {
+		usage: "subnets check <plan> <plan>...",
+		run:   runCheck,
+	},
+	"compact": {
+		usage: "subnets compact [--dry-run] [-o plan] <plan>",
+		run:   runCompact,
+	},
+	"convert": {
+		usage: "subnets convert <input> <output>",
+		run:   runConvert,
+	},
+	"diff": {
+		usage: "subnets diff [--json] <old plan> <new plan>",
+		run:   runDiff,
+	},
+	"export": {
+		usage: "subnets export [--format terraform|terraform-json|csv|tsv|kea|dnsmasq|rdns|bind|nft|ipset|aws-sg] [--name name] [--variable] [--intermediate] [--gateway first|last|none|<ip>] [--lease duration] [--ns ns1,ns2] [--hostmaster addr] [--dir dir] [--where query] [--set name=query]... [--table name] [-o file] <plan>",
+		run:   runExport,
+	},
+	"free": {
+		usage: "subnets free <plan>",
+		run:   runFree,
+	},
+	"import": {
+		usage: "subnets import (--root <cidr>[,<cidr>...] | --into <plan>) [--tsv] [-o plan] <sheet>",
+		run:   runImport,
+	},
+	"info": {
+		usage: "subnets info [--private] <cidr|address|plan>...",
+		run:   runInfo,
+	},
+	"merge": {
+		usage: "subnets merge [--path name] [-o plan] <base> <ours> <theirs>",
+		run:   runMerge,
+	},
+	"query": {
+		usage: "subnets query <plan> '<expr>'",
+		run:   runQuery,
+	},
+	"range2cidr": {
+		usage: "subnets range2cidr [--into plan] [--label 'labels key=value...'] <start> <end>",
+		run:   runRange2CIDR,
+	},
+	"routes": {
+		usage: "subnets routes --format ip|netstat|show [--check plan | -o plan] <file>...",
+		run:   runRoutes,
+	},
+	"serve": {
+		usage: "subnets serve [--file plan] [--root <cidr>[,<cidr>...]] [--addr :8080]",
+		run:   runServe,
+	},
+	"summarize": {
+		usage: "subnets summarize [file]...",
+		run:   runSummarize,
+	},
+	"usage": {
+		usage: "subnets usage --format neigh|arp|dhcpd|kea [-o plan] <plan> <file>...",
+		run:   runUsage,
+	},
+}
+
+// runConvert rewrites a plan in the format chosen by the output file's
+// extension, e.g. from nested JSON to a flat ".plan" file.
+func runConvert(args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	ws, err := subnet.LoadWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	return subnet.SaveWorkspace(ws, args[1])
+}
+
+// newWorkspace returns a workspace with an undivided root for each of the
+// comma separated prefixes.
+func newWorkspace(cidrs string) (*subnet.Workspace, error) {
+	ws := &subnet.Workspace{}
+	for _, cidr := range strings.Split(cidrs, ",") {
+		address, maskLen, err := subnet.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := ws.Add(address, maskLen); err != nil {
+			return nil, err
+		}
+	}
+	return ws, nil
+}
+
+// errUnchanged is returned by the function passed to updatePlan when it
+// made no change worth saving.
+var errUnchanged = errors.New("plan unchanged")
+
+// updatePlan applies f to the plan saved in file and saves the result to
+// output, or back to file if output is empty. Saving back goes through the
+// plan store, so f sees the latest plan and other subnets processes editing
+// the file wait for its lock. Nothing is saved if f returns an error.
+func updatePlan(file, output string, f func(ws *subnet.Workspace) error) error {
+	st, err := store.Open(file)
+	if err != nil {
+		return err
+	}
+	if output == "" || filepath.Clean(output) == filepath.Clean(file) {
+		_, err = st.Update(0, f)
+	} else {
+		ws, _ := st.Snapshot()
+		if err = f(ws); err == nil {
+			err = writePlan(output, ws)
+		}
+	}
+	if errors.Is(err, errUnchanged) {
+		return nil
+	}
+	return err
+}
+
+// writePlan saves ws to file under the plan store's lock, replacing what
+// the file holds.
+func writePlan(file string, ws *subnet.Workspace) error {
+	st, err := store.New(file, ws)
+	if err != nil {
+		return err
+	}
+	_, err = st.Replace(0, ws)
+	return err
+}
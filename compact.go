@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// runCompact joins the free leaves of a plan into the largest free blocks
+// they fill and lists the joins, or with --dry-run only lists them.
+func runCompact(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "list the joins without saving the plan")
+	output := fs.String("o", "", "plan file to write, defaults to the input plan")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	if *dryRun {
+		ws, err := subnet.LoadWorkspace(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		for _, c := range ws.Compact(true) {
+			fmt.Println(describeCompaction(c))
+		}
+		return nil
+	}
+	return updatePlan(fs.Arg(0), *output, func(ws *subnet.Workspace) error {
+		compactions := ws.Compact(false)
+		for _, c := range compactions {
+			fmt.Println(describeCompaction(c))
+		}
+		if len(compactions) == 0 {
+			return errUnchanged
+		}
+		return nil
+	})
+}
+
+func describeCompaction(c subnet.Compaction) string {
+	return fmt.Sprintf("%s from %d free subnets", c.Subnet.CIDR(), c.Leaves)
+}
+
+// compact joins the free leaves of the plan, asking to press the key again
+// after listing the joins.
+func (m *model) compact() {
+	compactions := m.workspace.Compact(!m.compacting)
+	if len(compactions) == 0 {
+		m.compacting = false
+		m.status = "Nothing to compact"
+		return
+	}
+	descriptions := make([]string, len(compactions))
+	for i, c := range compactions {
+		descriptions[i] = describeCompaction(c)
+	}
+	if !m.compacting {
+		m.compacting = true
+		m.status = fmt.Sprintf("Compacting joins %s; press %s again to compact", strings.Join(descriptions, ", "), m.KeyMap.Compact.Help().Key)
+		return
+	}
+	m.compacting = false
+	m.status = "Joined " + strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+var (
+	styleAdded   = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#008700", Dark: "#87d787"})
+	styleRemoved = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#af0000", Dark: "#ff8787"})
+)
+
+// diffSigns are the markers at the start of each line of text diff output.
+var diffSigns = map[subnet.ChangeKind]string{
+	subnet.Added:      "+",
+	subnet.Removed:    "-",
+	subnet.Split:      "<",
+	subnet.Joined:     ">",
+	subnet.Relabelled: "~",
+}
+
+// runDiff prints the changes between two plans.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print the changes as JSON")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	old, err := subnet.LoadWorkspace(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	new, err := subnet.LoadWorkspace(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	changes := subnet.DiffWorkspaces(old, new)
+	if *asJSON {
+		if changes == nil {
+			changes = []subnet.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+	for _, c := range changes {
+		fmt.Println(formatChange(c))
+	}
+	return nil
+}
+
+// formatChange renders a change as one coloured line.
+func formatChange(c subnet.Change) string {
+	line := diffSigns[c.Kind] + " " + c.CIDR
+	switch c.Kind {
+	case subnet.Added:
+		return styleAdded.Render(joinFields(line, attributes(c.NewLabels, c.NewMetadata)))
+	case subnet.Removed:
+		return styleRemoved.Render(joinFields(line, attributes(c.OldLabels, c.OldMetadata)))
+	case subnet.Relabelled:
+		old, new := attributes(c.OldLabels, c.OldMetadata), attributes(c.NewLabels, c.NewMetadata)
+		if old == "" {
+			old = "(none)"
+		}
+		if new == "" {
+			new = "(none)"
+		}
+		line += " " + old + " -> " + new
+		if !maps.Equal(c.OldHosts, c.NewHosts) {
+			line += fmt.Sprintf(" (hosts %d -> %d)", len(c.OldHosts), len(c.NewHosts))
+		}
+		return styleStatus.Render(line)
+	default:
+		return styleHelp.Render(line + " " + string(c.Kind))
+	}
+}
+
+// attributes formats labels and metadata as in a flat plan line.
+func attributes(labels []string, metadata map[string]string) string {
+	n := subnet.Subnet{Labels: labels, Metadata: metadata}
+	return strings.Join(n.Fields(), " ")
+}
+
+func joinFields(a, b string) string {
+	if b == "" {
+		return a
+	}
+	return a + " " + b
+}
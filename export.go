@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rochana-atapattu/subnets/internal/export"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// runExport writes a plan in one of the export formats to stdout or a file.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "terraform", "output format: terraform, terraform-json, csv, tsv, kea, dnsmasq, rdns, bind, nft, ipset or aws-sg")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	name := fs.String("name", "", "terraform local or variable name")
+	variable := fs.Bool("variable", false, "emit a terraform variable block instead of locals")
+	intermediate := fs.Bool("intermediate", false, "include divided subnets in csv and tsv output")
+	gateway := fs.String("gateway", "first", "default DHCP gateway: first, last, none or an address")
+	lease := fs.Duration("lease", 24*time.Hour, "default DHCP lease time")
+	nameservers := fs.String("ns", "", "comma separated nameservers for reverse zones")
+	hostmaster := fs.String("hostmaster", "", "SOA contact for reverse zones")
+	dir := fs.String("dir", "", "write one bind zone file per reverse zone into this directory")
+	table := fs.String("table", "", "nftables table name, filter if empty")
+	var sets []export.FirewallSet
+	where := fs.String("where", "", "only export the leaves matching this query")
+	fs.Func("set", "firewall set as name=query, such as prod_db=env=prod,role=db; repeatable, defaults to one set per label", func(s string) error {
+		set, err := export.ParseFirewallSet(s)
+		sets = append(sets, set)
+		return err
+	})
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	ws, err := subnet.LoadWorkspace(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var plan subnet.Plan = ws
+	if *where != "" {
+		q, err := subnet.ParseQuery(*where)
+		if err != nil {
+			return err
+		}
+		plan = subnet.Filter(ws, q)
+	}
+
+	rdns := export.RDNSOptions{Hostmaster: *hostmaster}
+	if *nameservers != "" {
+		rdns.Nameservers = strings.Split(*nameservers, ",")
+	}
+	if *format == "bind" && *dir != "" {
+		return writeZoneFiles(*dir, plan, rdns)
+	}
+
+	if len(sets) == 0 && (*format == "nft" || *format == "ipset" || *format == "aws-sg") {
+		if sets, err = export.LabelSets(plan); err != nil {
+			return fmt.Errorf("%w; name the sets with --set", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	tf := export.TerraformOptions{Name: *name, Variable: *variable}
+	switch *format {
+	case "terraform":
+		return export.WriteTerraformHCL(w, plan, tf)
+	case "terraform-json":
+		return export.WriteTerraformJSON(w, plan, tf)
+	case "csv":
+		return export.WriteCSV(w, plan, export.CSVOptions{Intermediate: *intermediate})
+	case "tsv":
+		return export.WriteCSV(w, plan, export.CSVOptions{Comma: '\t', Intermediate: *intermediate})
+	case "kea":
+		return export.WriteKea(w, plan, export.DHCPOptions{Gateway: *gateway, Lease: *lease})
+	case "dnsmasq":
+		return export.WriteDnsmasq(w, plan, export.DHCPOptions{Gateway: *gateway, Lease: *lease})
+	case "rdns":
+		return export.WriteReverseNames(w, plan, rdns)
+	case "bind":
+		return export.WriteZoneStubs(w, plan, rdns)
+	case "nft":
+		return export.WriteNftables(w, plan, sets, export.NftablesOptions{Table: *table})
+	case "ipset":
+		return export.WriteIpset(w, plan, sets)
+	case "aws-sg":
+		return export.WriteSecurityGroups(w, plan, sets)
+	default:
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+}
+
+// writeZoneFiles writes each reverse zone stub to its own file in dir, named
+// after the zone with "/" replaced by "-".
+func writeZoneFiles(dir string, plan subnet.Plan, opts export.RDNSOptions) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, z := range export.ReverseZones(plan, opts) {
+		name := strings.ReplaceAll(strings.TrimSuffix(z.Name, "."), "/", "-") + ".zone"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(export.ZoneStub(z, opts)), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runImport builds or extends a plan from a CSV or TSV sheet.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	rootCIDRs := fs.String("root", "", "start a new plan with these comma separated root prefixes")
+	into := fs.String("into", "", "import into this existing plan")
+	output := fs.String("o", "", "plan file to write, defaults to --into or subnets.json")
+	tsv := fs.Bool("tsv", false, "read tab separated values")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 1 || (*rootCIDRs == "") == (*into == "") {
+		return errUsage
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	opts := export.CSVOptions{}
+	if *tsv {
+		opts.Comma = '\t'
+	}
+	var problems []export.RowError
+	importCSV := func(ws *subnet.Workspace) error {
+		var err error
+		problems, err = export.ImportCSV(f, ws, opts)
+		return err
+	}
+
+	if *into != "" {
+		err = updatePlan(*into, *output, importCSV)
+	} else {
+		var ws *subnet.Workspace
+		if ws, err = newWorkspace(*rootCIDRs); err != nil {
+			return err
+		}
+		path := *output
+		if path == "" {
+			path = "subnets.json"
+		}
+		if err = importCSV(ws); err == nil {
+			err = writePlan(path, ws)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println("Skipped", p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d rows skipped", len(problems))
+	}
+	return nil
+}
+
+// exportTerraform writes the workspace's Terraform locals block to
+// subnets.tf for the TUI's export key.
+func (m *model) exportTerraform() {
+	f, err := os.Create("subnets.tf")
+	if err == nil {
+		err = export.WriteTerraformHCL(f, m.workspace, export.TerraformOptions{})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		m.status = fmt.Sprint("Error exporting subnet tree: ", err)
+		return
+	}
+	m.status = "Exported subnets.tf"
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+	"github.com/rochana-atapattu/subnets/internal/tree"
+)
+
+// startFilter shows the prompt for the query filtering the tree. An empty
+// query clears the filter.
+func (m *model) startFilter() tea.Cmd {
+	cmd := m.startPrompt("Filter: ", "env=prod AND prefix<=24", (*model).setFilter)
+	if m.filter != nil {
+		m.input.SetValue(m.filter.String())
+		m.input.CursorEnd()
+	}
+	return cmd
+}
+
+// setFilter filters the tree by the query entered at the prompt.
+func (m *model) setFilter(value string) {
+	if value == "" {
+		m.filter = nil
+		m.status = ""
+		return
+	}
+	q, err := subnet.ParseQuery(value)
+	if err != nil {
+		m.status = fmt.Sprint("Error: ", err)
+		return
+	}
+	m.filter = q
+	m.status = fmt.Sprintf("%d subnets match %s", len(subnet.Select(m.workspace, q)), q)
+}
+
+// filterRows lists the leaves matching the filter in address order.
+func (m *model) filterRows() []tree.Node {
+	leaves := subnet.Select(m.workspace, m.filter)
+	rows := make([]tree.Node, len(leaves))
+	for i, n := range leaves {
+		rows[i] = toNode(n)
+	}
+	return rows
+}
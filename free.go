@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+var styleFree = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).PaddingLeft(1)
+
+// runFree lists the free blocks of each root of a plan with the number of
+// free addresses.
+func runFree(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	ws, err := subnet.LoadWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	var free, total uint64
+	for _, root := range ws.Roots {
+		blocks := root.FreeBlocks()
+		n, size := freeAddresses(blocks), root.Size()
+		free += n
+		total += size
+		fmt.Printf("%s %s\n", describeSubnet(root), freeSummary(n, size))
+		for _, b := range blocks {
+			fmt.Printf("  %s\n", b.CIDR())
+		}
+	}
+	if len(ws.Roots) > 1 {
+		fmt.Printf("total %s\n", freeSummary(free, total))
+	}
+	return nil
+}
+
+func freeAddresses(blocks []*subnet.Subnet) uint64 {
+	var free uint64
+	for _, b := range blocks {
+		free += b.Size()
+	}
+	return free
+}
+
+func freeSummary(free, total uint64) string {
+	return fmt.Sprintf("free %d of %d addresses (%.1f%%)", free, total, 100*float64(free)/float64(total))
+}
+
+// freeView shows the free space of the workspace and, for each prefix
+// length of a free block, how many there are and the first of them.
+func (m model) freeView() string {
+	var blocks []*subnet.Subnet
+	var total uint64
+	for _, root := range m.workspace.Roots {
+		blocks = append(blocks, root.FreeBlocks()...)
+		total += root.Size()
+	}
+	lines := []string{"Free space", freeSummary(freeAddresses(blocks), total)}
+	first := make(map[uint32]*subnet.Subnet)
+	count := make(map[uint32]int)
+	for _, b := range blocks {
+		if first[b.MaskLen] == nil {
+			first[b.MaskLen] = b
+		}
+		count[b.MaskLen]++
+	}
+	for maskLen := uint32(0); maskLen <= 32; maskLen++ {
+		if b := first[maskLen]; b != nil {
+			lines = append(lines, fmt.Sprintf("/%-2d %4d free, first %s", maskLen, count[maskLen], b.CIDR()))
+		}
+	}
+	return styleFree.Render(strings.Join(lines, "\n"))
+}
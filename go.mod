@@ -1,6 +1,6 @@
 module github.com/rochana-atapattu/subnets
 
-go 1.21.6
+go 1.23
 
 require (
 	github.com/a-h/templ v0.2.543
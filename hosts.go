@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// showHosts opens the host assignments of the selected leaf.
+func (m *model) showHosts() {
+	n := m.selected()
+	if n == nil || n.Left != nil || n.Right != nil {
+		m.status = "Select a leaf to list its hosts"
+		return
+	}
+	m.hostsOf = n.CIDR()
+}
+
+// hostsLeaf returns the leaf whose hosts are shown, or nil if it is no longer
+// a leaf of the plan, for example after a reload.
+func (m model) hostsLeaf() *subnet.Subnet {
+	address, maskLen, err := subnet.ParseCIDR(m.hostsOf)
+	if err != nil {
+		return nil
+	}
+	n := m.workspace.Find(address, maskLen)
+	if n == nil || n.Left != nil || n.Right != nil {
+		return nil
+	}
+	return n
+}
+
+// updateHosts handles keys while the host assignments are shown.
+func (m *model) updateHosts(msg tea.KeyMsg) tea.Cmd {
+	n := m.hostsLeaf()
+	switch {
+	case n == nil, key.Matches(msg, m.KeyMap.Hosts), msg.Type == tea.KeyEsc:
+		m.hostsOf = ""
+	case key.Matches(msg, m.KeyMap.AssignHost):
+		address, err := n.NextFreeHost()
+		if err != nil {
+			m.status = err.Error()
+			return nil
+		}
+		return m.startPrompt(fmt.Sprintf("Host for %s: ", address), "name [mac] [note]", func(m *model, value string) {
+			if err := n.SetHost(address, parseHostInput(value)); err != nil {
+				m.status = fmt.Sprint("Error assigning host: ", err)
+				return
+			}
+			m.status = "Assigned " + address
+		})
+	}
+	return nil
+}
+
+// parseHostInput reads "name [mac] [note...]" as entered at the prompt.
+func parseHostInput(value string) subnet.Host {
+	fields := strings.Fields(value)
+	var h subnet.Host
+	if len(fields) > 0 {
+		h.Hostname, fields = fields[0], fields[1:]
+	}
+	if len(fields) > 0 && strings.Count(fields[0], ":") == 5 {
+		h.MAC, fields = fields[0], fields[1:]
+	}
+	h.Note = strings.Join(fields, " ")
+	return h
+}
+
+// hostsView lists the host assignments of the shown leaf.
+func (m model) hostsView() string {
+	n := m.hostsLeaf()
+	if n == nil {
+		return "No data"
+	}
+	first, last := subnet.UsableRange(n.Address, n.MaskLen)
+	lines := []string{fmt.Sprintf("Hosts of %s: %d of %d usable addresses assigned", joinFields(n.CIDR(), strings.Join(n.Labels, " ")), len(n.Hosts), last-first+1)}
+	if len(n.Hosts) == 0 {
+		lines = append(lines, styleHelp.Render("No hosts assigned"))
+	} else {
+		lines = append(lines, styleHelp.Render(fmt.Sprintf("%-15s  %-24s  %-17s  %s", "Address", "Hostname", "MAC", "Note")))
+	}
+	for _, address := range n.HostAddresses() {
+		h := n.Hosts[address]
+		lines = append(lines, fmt.Sprintf("%-15s  %-24s  %-17s  %s", address, h.Hostname, h.MAC, h.Note))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
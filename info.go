@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// runInfo describes addresses and prefixes, or the roots of plan files,
+// including how they relate to the special-purpose address blocks.
+func runInfo(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	private := fs.Bool("private", false, "warn about any subnet of a plan outside RFC 1918 space")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+	for i, arg := range fs.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+		if address, maskLen, ok := parsePrefix(arg); ok {
+			printPrefixInfo(address, maskLen)
+			continue
+		}
+		ws, err := subnet.LoadWorkspace(arg)
+		if err != nil {
+			return err
+		}
+		fmt.Println(arg)
+		for _, root := range ws.Roots {
+			fmt.Printf("  %s %s\n", describeSubnet(root), subnet.Classify(root.Address, root.MaskLen))
+		}
+		for _, w := range subnet.ScopeWarnings(ws, *private) {
+			fmt.Println("Warning:", w)
+		}
+	}
+	return nil
+}
+
+// parsePrefix parses a CIDR or a single address as a /32.
+func parsePrefix(s string) (uint32, uint32, bool) {
+	if !strings.Contains(s, "/") {
+		address, err := subnet.ParseIP(s)
+		return address, 32, err == nil
+	}
+	address, maskLen, err := subnet.ParseCIDR(s)
+	return address, maskLen, err == nil
+}
+
+func printPrefixInfo(address, maskLen uint32) {
+	network := subnet.NetworkAddress(address, maskLen)
+	first, last := subnet.UsableRange(network, maskLen)
+	fmt.Printf("Network:   %s\n", subnet.FormatCIDR(network, maskLen))
+	if network != address {
+		fmt.Printf("Address:   %s\n", subnet.InetNtoa(address))
+	}
+	fmt.Printf("Netmask:   %s\n", subnet.InetNtoa(subnet.SubnetNetmask(maskLen)))
+	fmt.Printf("Broadcast: %s\n", subnet.InetNtoa(subnet.SubnetLastAddress(network, maskLen)))
+	fmt.Printf("Usable:    %s - %s (%d hosts)\n", subnet.InetNtoa(first), subnet.InetNtoa(last), subnet.UsableHosts(maskLen))
+	fmt.Printf("Scope:     %s\n", subnet.Classify(address, maskLen))
+}
@@ -0,0 +1,222 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// CSVOptions controls WriteCSV and ImportCSV.
+type CSVOptions struct {
+	// Comma is the field separator, ',' if zero. Use '\t' for TSV.
+	Comma rune
+	// Intermediate also writes divided nodes, marked with leaf=false.
+	Intermediate bool
+}
+
+// csvColumns are the computed columns written before labels and metadata.
+// ImportCSV ignores them apart from cidr.
+var csvColumns = []string{"cidr", "netmask", "first_usable", "last_usable", "broadcast", "hosts"}
+
+// metadataPrefix starts the column of a metadata key that would otherwise
+// be read as a computed, labels or leaf column, or that starts with the
+// prefix itself.
+const metadataPrefix = "meta."
+
+// computedColumn reports whether a column with this header is written by
+// WriteCSV rather than holding metadata.
+func computedColumn(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return slices.Contains(csvColumns, name) || name == "labels" || name == "leaf"
+}
+
+// metadataColumn returns the header of the column holding metadata key k.
+func metadataColumn(k string) string {
+	if computedColumn(k) || strings.HasPrefix(strings.ToLower(k), metadataPrefix) {
+		return metadataPrefix + k
+	}
+	return k
+}
+
+// WriteCSV writes one row per leaf subnet with its address details, labels
+// (space separated and quoted as in flat plans) and one column per metadata
+// key used anywhere in the plan. Keys named like another column are written
+// with a "meta." prefix.
+func WriteCSV(w io.Writer, plan subnet.Plan, opts CSVOptions) error {
+	var nodes []*subnet.Subnet
+	plan.Walk(func(n *subnet.Subnet) {
+		if n.Left == nil && n.Right == nil || opts.Intermediate {
+			nodes = append(nodes, n)
+		}
+	})
+
+	keySet := make(map[string]string)
+	for _, n := range nodes {
+		for k := range n.Metadata {
+			keySet[k] = k
+		}
+	}
+	keys := subnet.SortedKeys(keySet)
+
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	header := append(append([]string{}, csvColumns...), "labels")
+	if opts.Intermediate {
+		header = append(header, "leaf")
+	}
+	for _, k := range keys {
+		header = append(header, metadataColumn(k))
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		first, last := subnet.UsableRange(n.Address, n.MaskLen)
+		row := []string{
+			n.CIDR(),
+			subnet.InetNtoa(subnet.SubnetNetmask(n.MaskLen)),
+			subnet.InetNtoa(first),
+			subnet.InetNtoa(last),
+			subnet.InetNtoa(subnet.SubnetLastAddress(n.Address, n.MaskLen)),
+			fmt.Sprint(subnet.UsableHosts(n.MaskLen)),
+			subnet.FormatLabels(n.Labels),
+		}
+		if opts.Intermediate {
+			row = append(row, strconv.FormatBool(n.Left == nil && n.Right == nil))
+		}
+		for _, k := range keys {
+			row = append(row, n.Metadata[k])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// RowError describes a row ImportCSV could not apply.
+type RowError struct {
+	Row  int
+	CIDR string
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d (%s): %v", e.Row, e.CIDR, e.Err)
+}
+
+// ImportCSV reads a sheet written by WriteCSV and divides the plan down to
+// each listed subnet, setting its labels and metadata. The "meta." prefix
+// WriteCSV adds to some metadata columns is removed. Rows that fail to
+// parse, fall outside the plan or overlap another row or an existing labelled
+// subnet are skipped and returned as RowErrors. Rows with leaf=false are
+// ignored.
+func ImportCSV(r io.Reader, plan subnet.Plan, opts CSVOptions) ([]RowError, error) {
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("sheet is empty")
+	}
+
+	header := records[0]
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	cidrCol, ok := columns["cidr"]
+	if !ok {
+		return nil, fmt.Errorf("sheet has no cidr column")
+	}
+	cell := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var problems []RowError
+	imported := make(map[*subnet.Subnet]int)
+	for i, record := range records[1:] {
+		row := i + 2
+		if cidrCol >= len(record) || strings.TrimSpace(record[cidrCol]) == "" {
+			continue
+		}
+		cidr := strings.TrimSpace(record[cidrCol])
+		if cell(record, "leaf") == "false" {
+			continue
+		}
+		fail := func(err error) {
+			problems = append(problems, RowError{Row: row, CIDR: cidr, Err: err})
+		}
+
+		address, maskLen, err := subnet.ParseCIDR(cidr)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		existing := plan.Locate(address, maskLen)
+		if existing == nil {
+			fail(fmt.Errorf("outside the plan"))
+			continue
+		}
+		if existing.Left != nil || existing.Right != nil {
+			fail(fmt.Errorf("overlaps more specific subnets of %s", existing.CIDR()))
+			continue
+		}
+		if other, ok := imported[existing]; ok {
+			fail(fmt.Errorf("overlaps %s on row %d", existing.CIDR(), other))
+			continue
+		}
+		if existing.MaskLen < maskLen && (len(existing.Labels) > 0 || len(existing.Metadata) > 0) {
+			fail(fmt.Errorf("overlaps labelled subnet %s", existing.CIDR()))
+			continue
+		}
+		labels, err := subnet.ParseLabels(cell(record, "labels"))
+		if err != nil {
+			fail(err)
+			continue
+		}
+		var metadata map[string]string
+		for name, col := range columns {
+			if computedColumn(name) || col >= len(record) || strings.TrimSpace(record[col]) == "" {
+				continue
+			}
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			k := strings.TrimSpace(header[col])
+			if strings.HasPrefix(name, metadataPrefix) {
+				k = k[len(metadataPrefix):]
+			}
+			metadata[k] = strings.TrimSpace(record[col])
+		}
+		if err := subnet.CheckMetadata(metadata); err != nil {
+			fail(err)
+			continue
+		}
+		node, err := plan.DivideTo(address, maskLen)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		node.Labels = labels
+		node.Metadata = metadata
+		imported[node] = row
+	}
+	return problems, nil
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testPlan(), CSVOptions{}); err != nil {
+		t.Fatalf("WriteCSV returned error: %v", err)
+	}
+	want := `cidr,netmask,first_usable,last_usable,broadcast,hosts,labels,az
+10.0.0.0/24,255.255.255.0,10.0.0.1,10.0.0.254,10.0.0.255,254,web prod,
+10.0.1.0/25,255.255.255.128,10.0.1.1,10.0.1.126,10.0.1.127,126,db,a
+10.0.1.128/25,255.255.255.128,10.0.1.129,10.0.1.254,10.0.1.255,126,,
+10.0.2.0/23,255.255.254.0,10.0.2.1,10.0.3.254,10.0.3.255,510,,
+`
+	if buf.String() != want {
+		t.Errorf("WriteCSV wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteCSV(&buf, testPlan(), CSVOptions{Comma: '\t', Intermediate: true}); err != nil {
+		t.Fatalf("WriteCSV returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[1], "10.0.0.0/22\t") || !strings.Contains(lines[1], "\tfalse\t") {
+		t.Errorf("WriteCSV with intermediate nodes wrote\n%s", buf.String())
+	}
+}
+
+func TestImportCSVRoundTrip(t *testing.T) {
+	for _, opts := range []CSVOptions{{}, {Comma: '\t', Intermediate: true}} {
+		var buf bytes.Buffer
+		WriteCSV(&buf, testPlan(), opts)
+
+		root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 22}
+		problems, err := ImportCSV(&buf, root, opts)
+		if err != nil || len(problems) != 0 {
+			t.Fatalf("ImportCSV = %v, %v; want no problems", problems, err)
+		}
+		var got, want bytes.Buffer
+		subnet.WriteFlat(&got, root)
+		subnet.WriteFlat(&want, testPlan())
+		if got.String() != want.String() {
+			t.Errorf("ImportCSV(%+v) rebuilt\n%s\nwant\n%s", opts, got.String(), want.String())
+		}
+	}
+}
+
+func TestImportCSVProblems(t *testing.T) {
+	sheet := `CIDR,Labels,owner
+10.0.0.0/24,web,alice
+10.0.0.0/25,web-a,
+10.9.0.0/24,elsewhere,
+10.0.1.0/33,bad,
+10.0.2.0/24,,bob
+`
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 16}
+	problems, err := ImportCSV(strings.NewReader(sheet), root, CSVOptions{})
+	if err != nil {
+		t.Fatalf("ImportCSV returned error: %v", err)
+	}
+	var rows []int
+	for _, p := range problems {
+		rows = append(rows, p.Row)
+	}
+	if len(rows) != 3 || rows[0] != 3 || rows[1] != 4 || rows[2] != 5 {
+		t.Errorf("ImportCSV problems on rows %v; want [3 4 5]: %v", rows, problems)
+	}
+	web := root.Find(subnet.InetAton("10.0.0.0"), 24)
+	if web == nil || web.Metadata["owner"] != "alice" || len(web.Labels) != 1 {
+		t.Errorf("ImportCSV 10.0.0.0/24 = %+v; want labelled web with owner alice", web)
+	}
+	if n := root.Find(subnet.InetAton("10.0.2.0"), 24); n == nil || n.Metadata["owner"] != "bob" {
+		t.Errorf("ImportCSV did not import 10.0.2.0/24")
+	}
+}
+
+func TestImportCSVWorkspace(t *testing.T) {
+	sheet := `cidr,labels
+10.0.1.0/24,web
+192.168.0.0/25,lab
+172.16.0.0/24,elsewhere
+`
+	ws, _ := subnet.NewWorkspace(
+		&subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 16},
+		&subnet.Subnet{Address: subnet.InetAton("192.168.0.0"), MaskLen: 24},
+	)
+	problems, err := ImportCSV(strings.NewReader(sheet), ws, CSVOptions{})
+	if err != nil {
+		t.Fatalf("ImportCSV returned error: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Row != 4 {
+		t.Errorf("ImportCSV problems = %v; want row 4 outside the plan", problems)
+	}
+	for _, cidr := range []string{"10.0.1.0/24", "192.168.0.0/25"} {
+		address, maskLen, _ := subnet.ParseCIDR(cidr)
+		if n := ws.Find(address, maskLen); n == nil || len(n.Labels) != 1 {
+			t.Errorf("ImportCSV did not import %s", cidr)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, ws, CSVOptions{}); err != nil {
+		t.Fatalf("WriteCSV returned error: %v", err)
+	}
+	if rows := strings.Count(buf.String(), "\n"); rows != 12 {
+		t.Errorf("WriteCSV wrote %d lines; want a header and 11 leaves:\n%s", rows, buf.String())
+	}
+}
+
+func TestImportCSVReservedKeys(t *testing.T) {
+	sheet := "cidr,used,\n10.0.0.0/24,5,\n10.0.1.0/24,,x\n10.0.2.0/24,,\n"
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 16}
+	problems, err := ImportCSV(strings.NewReader(sheet), root, CSVOptions{})
+	if err != nil {
+		t.Fatalf("ImportCSV returned error: %v", err)
+	}
+	if len(problems) != 2 || problems[0].Row != 2 || problems[1].Row != 3 {
+		t.Errorf("ImportCSV problems = %v; want rows 2 and 3", problems)
+	}
+	if n := root.Find(subnet.InetAton("10.0.0.0"), 24); n != nil && n.Metadata != nil {
+		t.Errorf("ImportCSV set metadata %v from a used column", n.Metadata)
+	}
+}
+
+func TestCSVQuotingAndColumnNames(t *testing.T) {
+	plan := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 23}
+	plan.Divide()
+	plan.Left.Labels = []string{"web tier", "a=b", "prod"}
+	plan.Left.Metadata = map[string]string{"hosts": "12", "Labels": "x", "meta.owner": "ops", "az": "a"}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, plan, CSVOptions{}); err != nil {
+		t.Fatalf("WriteCSV returned error: %v", err)
+	}
+	want := `cidr,netmask,first_usable,last_usable,broadcast,hosts,labels,meta.Labels,az,meta.hosts,meta.meta.owner
+10.0.0.0/24,255.255.255.0,10.0.0.1,10.0.0.254,10.0.0.255,254,"""web tier"" ""a=b"" prod",x,a,12,ops
+10.0.1.0/24,255.255.255.0,10.0.1.1,10.0.1.254,10.0.1.255,254,,,,,
+`
+	if buf.String() != want {
+		t.Errorf("WriteCSV wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 23}
+	problems, err := ImportCSV(&buf, root, CSVOptions{})
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("ImportCSV = %v, %v; want no problems", problems, err)
+	}
+	if !subnet.Equal(root, plan) {
+		t.Errorf("ImportCSV rebuilt %q %v; want %q %v", root.Left.Labels, root.Left.Metadata, plan.Left.Labels, plan.Left.Metadata)
+	}
+}
@@ -0,0 +1,309 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// DHCPOptions holds the defaults for leaves that do not override them with
+// "gateway", "exclude" or "lease" metadata.
+type DHCPOptions struct {
+	// Gateway is "first" (the default), "last", "none" or an address.
+	Gateway string
+	// Lease is the lease time, 24h if zero.
+	Lease time.Duration
+}
+
+// addrRange is an inclusive range of addresses.
+type addrRange struct {
+	first, last uint32
+}
+
+func (r addrRange) String() string {
+	return subnet.InetNtoa(r.first) + " - " + subnet.InetNtoa(r.last)
+}
+
+// dhcpScope is a leaf subnet served by DHCP.
+type dhcpScope struct {
+	subnet  *subnet.Subnet
+	gateway string
+	pools   []addrRange
+	lease   time.Duration
+}
+
+// isTrue reports whether a metadata value switches a flag on.
+func isTrue(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// parseAddrRange parses "10.0.0.5" or "10.0.0.5-10.0.0.9".
+func parseAddrRange(s string) (addrRange, error) {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		last = first
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+	var r addrRange
+	var err error
+	if r.first, err = subnet.ParseIP(first); err == nil {
+		r.last, err = subnet.ParseIP(last)
+	}
+	if err != nil {
+		return addrRange{}, fmt.Errorf("invalid address range %q", s)
+	}
+	if r.first > r.last {
+		return addrRange{}, fmt.Errorf("invalid address range %q", s)
+	}
+	return r, nil
+}
+
+// dhcpScopes returns a scope for every leaf with the "dhcp" metadata flag,
+// with pools covering its usable range minus the gateway and exclusions.
+func dhcpScopes(plan subnet.Plan, opts DHCPOptions) ([]dhcpScope, error) {
+	var scopes []dhcpScope
+	var err error
+	plan.Iterate(func(n *subnet.Subnet) {
+		if err != nil || !isTrue(n.Metadata["dhcp"]) {
+			return
+		}
+		var scope dhcpScope
+		scope, err = newDHCPScope(n, opts)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", n.CIDR(), err)
+			return
+		}
+		scopes = append(scopes, scope)
+	})
+	return scopes, err
+}
+
+func newDHCPScope(n *subnet.Subnet, opts DHCPOptions) (dhcpScope, error) {
+	first, last := subnet.UsableRange(n.Address, n.MaskLen)
+	usable := addrRange{first, last}
+	scope := dhcpScope{subnet: n, lease: opts.Lease}
+	if scope.lease == 0 {
+		scope.lease = 24 * time.Hour
+	}
+	if v := n.Metadata["lease"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			secs, serr := strconv.Atoi(v)
+			if serr != nil {
+				return scope, fmt.Errorf("invalid lease time %q", v)
+			}
+			d = time.Duration(secs) * time.Second
+		}
+		scope.lease = d
+	}
+
+	var excluded []addrRange
+	gateway := opts.Gateway
+	if v := n.Metadata["gateway"]; v != "" {
+		gateway = v
+	}
+	switch gateway {
+	case "", "first":
+		scope.gateway = subnet.InetNtoa(first)
+	case "last":
+		scope.gateway = subnet.InetNtoa(last)
+	case "none":
+	default:
+		if _, err := subnet.ParseIP(gateway); err != nil {
+			return scope, fmt.Errorf("invalid gateway %q", gateway)
+		}
+		scope.gateway = gateway
+	}
+	if scope.gateway != "" {
+		addr := subnet.InetAton(scope.gateway)
+		if addr < first || addr > last {
+			return scope, fmt.Errorf("gateway %s is outside %s", scope.gateway, usable)
+		}
+		excluded = append(excluded, addrRange{addr, addr})
+	}
+
+	if v := n.Metadata["exclude"]; v != "" {
+		for _, part := range strings.Split(v, ",") {
+			r, err := parseAddrRange(part)
+			if err != nil {
+				return scope, err
+			}
+			if r.first < n.Address || r.last > subnet.SubnetLastAddress(n.Address, n.MaskLen) {
+				return scope, fmt.Errorf("excluded range %s is outside the subnet", r)
+			}
+			excluded = append(excluded, r)
+		}
+	}
+
+	scope.pools = subtractRanges(usable, excluded)
+	if len(scope.pools) == 0 {
+		return scope, fmt.Errorf("no addresses left after exclusions")
+	}
+	return scope, nil
+}
+
+// subtractRanges returns the parts of r not covered by any of excluded.
+func subtractRanges(r addrRange, excluded []addrRange) []addrRange {
+	pools := []addrRange{r}
+	for _, x := range excluded {
+		var next []addrRange
+		for _, p := range pools {
+			if x.last < p.first || x.first > p.last {
+				next = append(next, p)
+				continue
+			}
+			if x.first > p.first {
+				next = append(next, addrRange{p.first, x.first - 1})
+			}
+			if x.last < p.last {
+				next = append(next, addrRange{x.last + 1, p.last})
+			}
+		}
+		pools = next
+	}
+	return pools
+}
+
+// keaSubnet is an entry of Kea's Dhcp4 "subnet4" list.
+type keaSubnet struct {
+	ID            uint32            `json:"id"`
+	Subnet        string            `json:"subnet"`
+	Pools         []keaPool         `json:"pools"`
+	OptionData    []keaOption       `json:"option-data,omitempty"`
+	ValidLifetime int               `json:"valid-lifetime"`
+	UserContext   map[string]string `json:"user-context,omitempty"`
+}
+
+type keaPool struct {
+	Pool string `json:"pool"`
+}
+
+type keaOption struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+// WriteKea writes the DHCP-enabled leaves as a JSON array of Kea Dhcp4
+// "subnet4" entries, numbered from 1 in address order.
+func WriteKea(w io.Writer, plan subnet.Plan, opts DHCPOptions) error {
+	scopes, err := dhcpScopes(plan, opts)
+	if err != nil {
+		return err
+	}
+	entries := []keaSubnet{}
+	ids := make(map[uint32]string)
+	for _, scope := range scopes {
+		id, err := keaID(scope.subnet)
+		if err != nil {
+			return fmt.Errorf("%s: %w", scope.subnet.CIDR(), err)
+		}
+		if other, ok := ids[id]; ok {
+			return fmt.Errorf("%s and %s both have subnet id %d", other, scope.subnet.CIDR(), id)
+		}
+		ids[id] = scope.subnet.CIDR()
+		entry := keaSubnet{
+			ID:            id,
+			Subnet:        scope.subnet.CIDR(),
+			ValidLifetime: int(scope.lease.Seconds()),
+		}
+		for _, p := range scope.pools {
+			entry.Pools = append(entry.Pools, keaPool{p.String()})
+		}
+		if scope.gateway != "" {
+			entry.OptionData = []keaOption{{Name: "routers", Data: scope.gateway}}
+		}
+		if name := Name(scope.subnet); name != "" {
+			entry.UserContext = map[string]string{"name": name}
+		}
+		entries = append(entries, entry)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// keaID returns the Kea subnet-id of a leaf: its "id" metadata if set,
+// otherwise its network address as a number. Leases refer to subnets by id,
+// so the id must not change when other subnets are added.
+func keaID(n *subnet.Subnet) (uint32, error) {
+	v, ok := n.Metadata["id"]
+	if !ok {
+		if n.Address == 0 {
+			return 0, fmt.Errorf("subnet id 0 is reserved; set \"id\" metadata")
+		}
+		return n.Address, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || id == 0 || id == math.MaxUint32 {
+		return 0, fmt.Errorf("invalid subnet id %q", v)
+	}
+	return uint32(id), nil
+}
+
+// WriteDnsmasq writes a dhcp-range line per pool of each DHCP-enabled leaf,
+// tagged with the leaf's name and CIDR, and a matching router option.
+func WriteDnsmasq(w io.Writer, plan subnet.Plan, opts DHCPOptions) error {
+	scopes, err := dhcpScopes(plan, opts)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	tags := make(map[string]string)
+	for _, scope := range scopes {
+		n := scope.subnet
+		tag := dnsmasqTag(n)
+		if other, ok := tags[tag]; ok {
+			return fmt.Errorf("%s and %s both have dnsmasq tag %s", other, n.CIDR(), tag)
+		}
+		tags[tag] = n.CIDR()
+		netmask := subnet.InetNtoa(subnet.SubnetNetmask(n.MaskLen))
+		fmt.Fprintf(&b, "# %s\n", strings.Join(append([]string{n.CIDR()}, n.Labels...), " "))
+		for _, p := range scope.pools {
+			fmt.Fprintf(&b, "dhcp-range=set:%s,%s,%s,%s,%s\n", tag, subnet.InetNtoa(p.first), subnet.InetNtoa(p.last), netmask, dnsmasqLease(scope.lease))
+		}
+		if scope.gateway != "" {
+			fmt.Fprintf(&b, "dhcp-option=tag:%s,option:router,%s\n", tag, scope.gateway)
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// dnsmasqTag returns a tag safe for dnsmasq: the leaf's name followed by its
+// CIDR, so leaves sharing labels get tags of their own, or "net" and the
+// CIDR when it has no name.
+func dnsmasqTag(n *subnet.Subnet) string {
+	name := Name(n)
+	if name == "" {
+		name = "net"
+	}
+	name += "-" + n.CIDR()
+	return strings.Map(func(r rune) rune {
+		if r == ',' || r == ' ' || r == '.' || r == '/' || r == ':' {
+			return '-'
+		}
+		return r
+	}, name)
+}
+
+// dnsmasqLease formats a lease time in dnsmasq's h/m/seconds syntax.
+func dnsmasqLease(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprint(int(d.Seconds()))
+	}
+}
@@ -0,0 +1,164 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// dhcpPlan returns testPlan with DHCP enabled on the web and db leaves.
+func dhcpPlan() *subnet.Subnet {
+	root := testPlan()
+	web := root.Find(subnet.InetAton("10.0.0.0"), 24)
+	web.Metadata = map[string]string{"dhcp": "true", "exclude": "10.0.0.10-10.0.0.19,10.0.0.254"}
+	db := root.Find(subnet.InetAton("10.0.1.0"), 25)
+	db.Metadata = map[string]string{"dhcp": "yes", "gateway": "10.0.1.126", "lease": "90m"}
+	return root
+}
+
+func TestWriteKea(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteKea(&buf, dhcpPlan(), DHCPOptions{}); err != nil {
+		t.Fatalf("WriteKea returned error: %v", err)
+	}
+	want := `[
+  {
+    "id": 167772160,
+    "subnet": "10.0.0.0/24",
+    "pools": [
+      {
+        "pool": "10.0.0.2 - 10.0.0.9"
+      },
+      {
+        "pool": "10.0.0.20 - 10.0.0.253"
+      }
+    ],
+    "option-data": [
+      {
+        "name": "routers",
+        "data": "10.0.0.1"
+      }
+    ],
+    "valid-lifetime": 86400,
+    "user-context": {
+      "name": "web-prod"
+    }
+  },
+  {
+    "id": 167772416,
+    "subnet": "10.0.1.0/25",
+    "pools": [
+      {
+        "pool": "10.0.1.1 - 10.0.1.125"
+      }
+    ],
+    "option-data": [
+      {
+        "name": "routers",
+        "data": "10.0.1.126"
+      }
+    ],
+    "valid-lifetime": 5400,
+    "user-context": {
+      "name": "db"
+    }
+  }
+]
+`
+	if buf.String() != want {
+		t.Errorf("WriteKea wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestKeaIDs(t *testing.T) {
+	ids := func(root *subnet.Subnet) map[string]uint32 {
+		var buf bytes.Buffer
+		if err := WriteKea(&buf, root, DHCPOptions{}); err != nil {
+			t.Fatalf("WriteKea returned error: %v", err)
+		}
+		var entries []keaSubnet
+		if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+			t.Fatalf("WriteKea wrote invalid JSON: %v", err)
+		}
+		ids := make(map[string]uint32)
+		for _, e := range entries {
+			ids[e.Subnet] = e.ID
+		}
+		return ids
+	}
+
+	// Serving another subnet before db keeps db's id.
+	root := dhcpPlan()
+	web := root.Find(subnet.InetAton("10.0.0.0"), 24)
+	delete(web.Metadata, "dhcp")
+	before := ids(root)
+	web.Metadata["dhcp"] = "true"
+	after := ids(root)
+	if len(after) != 2 || after["10.0.1.0/25"] != before["10.0.1.0/25"] {
+		t.Errorf("ids changed from %v to %v", before, after)
+	}
+
+	web.Metadata["id"] = "7"
+	if got := ids(root); got["10.0.0.0/24"] != 7 {
+		t.Errorf("id metadata ignored: %v", got)
+	}
+	web.Metadata["id"] = fmt.Sprint(subnet.InetAton("10.0.1.0"))
+	if err := WriteKea(io.Discard, root, DHCPOptions{}); err == nil {
+		t.Errorf("WriteKea with duplicate ids succeeded; want error")
+	}
+}
+
+func TestWriteDnsmasq(t *testing.T) {
+	var buf bytes.Buffer
+	opts := DHCPOptions{Gateway: "none", Lease: 12 * time.Hour}
+	if err := WriteDnsmasq(&buf, dhcpPlan(), opts); err != nil {
+		t.Fatalf("WriteDnsmasq returned error: %v", err)
+	}
+	want := `# 10.0.0.0/24 web prod
+dhcp-range=set:web-prod-10-0-0-0-24,10.0.0.1,10.0.0.9,255.255.255.0,12h
+dhcp-range=set:web-prod-10-0-0-0-24,10.0.0.20,10.0.0.253,255.255.255.0,12h
+# 10.0.1.0/25 db
+dhcp-range=set:db-10-0-1-0-25,10.0.1.1,10.0.1.125,255.255.255.128,90m
+dhcp-option=tag:db-10-0-1-0-25,option:router,10.0.1.126
+`
+	if buf.String() != want {
+		t.Errorf("WriteDnsmasq wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// Leaves with the same labels get separate tags.
+	root := dhcpPlan()
+	db := root.Find(subnet.InetAton("10.0.1.0"), 25)
+	db.Labels = []string{"web", "prod"}
+	buf.Reset()
+	if err := WriteDnsmasq(&buf, root, opts); err != nil {
+		t.Fatalf("WriteDnsmasq returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "tag:web-prod-10-0-1-0-25,option:router,10.0.1.126") {
+		t.Errorf("WriteDnsmasq with shared labels wrote\n%s", buf.String())
+	}
+}
+
+func TestDHCPErrors(t *testing.T) {
+	testCases := []map[string]string{
+		{"dhcp": "true", "gateway": "10.0.9.1"},
+		{"dhcp": "true", "exclude": "10.0.1.0-10.0.2.0"},
+		{"dhcp": "true", "lease": "soon"},
+		{"dhcp": "true", "exclude": "10.0.1.0-10.0.1.127"},
+		{"dhcp": "true", "id": "0"},
+		{"dhcp": "true", "id": "db"},
+	}
+	for _, metadata := range testCases {
+		root := testPlan()
+		root.Find(subnet.InetAton("10.0.1.0"), 25).Metadata = metadata
+		var buf bytes.Buffer
+		if err := WriteKea(&buf, root, DHCPOptions{}); err == nil {
+			t.Errorf("WriteKea with metadata %v succeeded; want error", metadata)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// FirewallSet is a named set of the leaves matching a selector or query,
+// exported as an nftables set, an ipset or a security group rule.
+type FirewallSet struct {
+	Name     string
+	Selector subnet.Matcher
+}
+
+// setName matches names valid for both nftables sets and ipsets.
+var setName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,30}$`)
+
+// setNameChars matches the characters not allowed in set names.
+var setNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ParseFirewallSet parses "name=query", such as "prod_db=env=prod,role=db"
+// or "edge=role in (lb,proxy) AND env=prod".
+func ParseFirewallSet(s string) (FirewallSet, error) {
+	name, selector, ok := strings.Cut(s, "=")
+	if !ok {
+		return FirewallSet{}, fmt.Errorf("invalid set %q: want name=selector", s)
+	}
+	if !setName.MatchString(name) {
+		return FirewallSet{}, fmt.Errorf("invalid set name %q", name)
+	}
+	q, err := subnet.ParseQuery(selector)
+	if err != nil {
+		return FirewallSet{}, err
+	}
+	return FirewallSet{name, q}, nil
+}
+
+// LabelSets returns a set for every label used in the plan, sorted by name.
+// Characters not allowed in set names are replaced with "_". It fails on an
+// empty label and if two labels end up with the same set name, such as "a.b"
+// and "a_b".
+func LabelSets(plan subnet.Plan) ([]FirewallSet, error) {
+	var labels []string
+	for n := range subnet.Leaves(plan) {
+		for _, label := range n.Labels {
+			if label == "" {
+				return nil, fmt.Errorf("%s has an empty label", n.CIDR())
+			}
+			if !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	slices.Sort(labels)
+	sets := make([]FirewallSet, len(labels))
+	named := make(map[string]string)
+	for i, label := range labels {
+		name := setNameChars.ReplaceAllString(label, "_")
+		if !setName.MatchString(name[:1]) {
+			name = "l" + name
+		}
+		name = name[:min(len(name), 31)]
+		if other, ok := named[name]; ok {
+			return nil, fmt.Errorf("labels %q and %q both make set %s", other, label, name)
+		}
+		named[name] = label
+		sets[i] = FirewallSet{name, subnet.Selector{Labels: []string{label}}}
+	}
+	return sets, nil
+}
+
+// cidrs returns the CIDRs of the leaves selected by set.
+func (set FirewallSet) cidrs(plan subnet.Plan) []string {
+	var cidrs []string
+	for _, n := range subnet.Select(plan, set.Selector) {
+		cidrs = append(cidrs, n.CIDR())
+	}
+	return cidrs
+}
+
+// NftablesOptions controls WriteNftables.
+type NftablesOptions struct {
+	// Family is the table family, "inet" if empty.
+	Family string
+	// Table is the table name, "filter" if empty.
+	Table string
+}
+
+// WriteNftables writes a table holding one interval set of IPv4 prefixes per
+// firewall set, for loading with "nft -f" or including in a ruleset.
+func WriteNftables(w io.Writer, plan subnet.Plan, sets []FirewallSet, opts NftablesOptions) error {
+	family, table := opts.Family, opts.Table
+	if family == "" {
+		family = "inet"
+	}
+	if table == "" {
+		table = "filter"
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "table %s %s {\n", family, table)
+	for i, set := range sets {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		if selector := set.Selector.String(); selector != "" {
+			fmt.Fprintf(bw, "\t# %s\n", selector)
+		}
+		fmt.Fprintf(bw, "\tset %s {\n", set.Name)
+		fmt.Fprintf(bw, "\t\ttype ipv4_addr\n")
+		fmt.Fprintf(bw, "\t\tflags interval\n")
+		if cidrs := set.cidrs(plan); len(cidrs) > 0 {
+			fmt.Fprintf(bw, "\t\telements = { %s }\n", strings.Join(cidrs, ", "))
+		}
+		fmt.Fprintf(bw, "\t}\n")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteIpset writes an "ipset restore" file that creates, empties and fills
+// a hash:net set per firewall set.
+func WriteIpset(w io.Writer, plan subnet.Plan, sets []FirewallSet) error {
+	bw := bufio.NewWriter(w)
+	for _, set := range sets {
+		fmt.Fprintf(bw, "create %s hash:net family inet comment -exist\n", set.Name)
+		fmt.Fprintf(bw, "flush %s\n", set.Name)
+		for _, n := range subnet.Select(plan, set.Selector) {
+			line := fmt.Sprintf("add %s %s -exist", set.Name, n.CIDR())
+			if name := Name(n); name != "" {
+				line += fmt.Sprintf(" comment %q", name)
+			}
+			fmt.Fprintln(bw, line)
+		}
+	}
+	return bw.Flush()
+}
+
+// ipPermission is an entry of the IpPermissions list accepted by the AWS EC2
+// security group API and "aws ec2 authorize-security-group-ingress".
+type ipPermission struct {
+	IpProtocol string    `json:"IpProtocol"`
+	IpRanges   []ipRange `json:"IpRanges"`
+}
+
+type ipRange struct {
+	CidrIp      string `json:"CidrIp"`
+	Description string `json:"Description,omitempty"`
+}
+
+// WriteSecurityGroups writes a JSON object mapping each set name to
+// IpPermissions allowing all traffic from the set's CIDRs, described by the
+// leaves' names.
+func WriteSecurityGroups(w io.Writer, plan subnet.Plan, sets []FirewallSet) error {
+	groups := make(map[string][]ipPermission)
+	for _, set := range sets {
+		ranges := []ipRange{}
+		for _, n := range subnet.Select(plan, set.Selector) {
+			ranges = append(ranges, ipRange{n.CIDR(), Name(n)})
+		}
+		groups[set.Name] = []ipPermission{{"-1", ranges}}
+	}
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
@@ -0,0 +1,156 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// firewallSets returns the label sets of testPlan and a set selecting by
+// metadata.
+func firewallSets(t *testing.T) []FirewallSet {
+	set, err := ParseFirewallSet("db_a=db,az=a")
+	if err != nil {
+		t.Fatalf("ParseFirewallSet returned error: %v", err)
+	}
+	sets, err := LabelSets(testPlan())
+	if err != nil {
+		t.Fatalf("LabelSets returned error: %v", err)
+	}
+	return append(sets, set)
+}
+
+func TestParseFirewallSet(t *testing.T) {
+	set, err := ParseFirewallSet("prod-db=env=prod,role=db")
+	if err != nil {
+		t.Fatalf("ParseFirewallSet returned error: %v", err)
+	}
+	if set.Name != "prod-db" || set.Selector.String() != "env=prod,role=db" {
+		t.Errorf("ParseFirewallSet = %q %q", set.Name, set.Selector)
+	}
+	for _, bad := range []string{"prod", "1db=role=db", "db=role=db,", "a.b=db"} {
+		if _, err := ParseFirewallSet(bad); err == nil {
+			t.Errorf("ParseFirewallSet(%q) returned no error", bad)
+		}
+	}
+}
+
+func TestLabelSets(t *testing.T) {
+	root := testPlan()
+	root.Find(subnet.InetAton("10.0.1.0"), 25).Labels = []string{"db", "9 lives"}
+	sets, err := LabelSets(root)
+	if err != nil {
+		t.Fatalf("LabelSets returned error: %v", err)
+	}
+	var got []string
+	for _, set := range sets {
+		got = append(got, set.Name+"="+set.Selector.String())
+	}
+	want := []string{"l9_lives=9 lives", "db=db", "prod=prod", "web=web"}
+	if len(got) != len(want) {
+		t.Fatalf("LabelSets = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("LabelSets = %q, want %q", got, want)
+			break
+		}
+	}
+
+	for _, labels := range [][]string{{""}, {"a.b", "a_b"}, {"9x", "l9x"}, {strings.Repeat("a", 31) + "1", strings.Repeat("a", 31) + "2"}} {
+		root.Find(subnet.InetAton("10.0.1.0"), 25).Labels = labels
+		if _, err := LabelSets(root); err == nil {
+			t.Errorf("LabelSets with labels %q succeeded; want error", labels)
+		}
+	}
+}
+
+func TestWriteNftables(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteNftables(&buf, testPlan(), firewallSets(t), NftablesOptions{}); err != nil {
+		t.Fatalf("WriteNftables returned error: %v", err)
+	}
+	want := `table inet filter {
+	# db
+	set db {
+		type ipv4_addr
+		flags interval
+		elements = { 10.0.1.0/25 }
+	}
+
+	# prod
+	set prod {
+		type ipv4_addr
+		flags interval
+		elements = { 10.0.0.0/24 }
+	}
+
+	# web
+	set web {
+		type ipv4_addr
+		flags interval
+		elements = { 10.0.0.0/24 }
+	}
+
+	# db,az=a
+	set db_a {
+		type ipv4_addr
+		flags interval
+		elements = { 10.0.1.0/25 }
+	}
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteNftables =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteIpset(t *testing.T) {
+	var buf bytes.Buffer
+	sets := []FirewallSet{{"web", subnet.Selector{Labels: []string{"web"}}}, {"none", subnet.Selector{Labels: []string{"none"}}}}
+	if err := WriteIpset(&buf, testPlan(), sets); err != nil {
+		t.Fatalf("WriteIpset returned error: %v", err)
+	}
+	want := `create web hash:net family inet comment -exist
+flush web
+add web 10.0.0.0/24 -exist comment "web-prod"
+create none hash:net family inet comment -exist
+flush none
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteIpset =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteSecurityGroups(t *testing.T) {
+	var buf bytes.Buffer
+	sets := []FirewallSet{{"web", subnet.Selector{Labels: []string{"web"}}}, {"none", subnet.Selector{Labels: []string{"none"}}}}
+	if err := WriteSecurityGroups(&buf, testPlan(), sets); err != nil {
+		t.Fatalf("WriteSecurityGroups returned error: %v", err)
+	}
+	want := `{
+  "none": [
+    {
+      "IpProtocol": "-1",
+      "IpRanges": []
+    }
+  ],
+  "web": [
+    {
+      "IpProtocol": "-1",
+      "IpRanges": [
+        {
+          "CidrIp": "10.0.0.0/24",
+          "Description": "web-prod"
+        }
+      ]
+    }
+  ]
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteSecurityGroups =\n%s\nwant\n%s", got, want)
+	}
+}
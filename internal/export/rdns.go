@@ -0,0 +1,147 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// RDNSOptions controls the reverse DNS exporters.
+type RDNSOptions struct {
+	// Nameservers are the zones' NS records, "ns1.example.com." if empty.
+	Nameservers []string
+	// Hostmaster is the SOA contact, "hostmaster.example.com." if empty.
+	Hostmaster string
+	// TTL is the zones' default TTL in seconds, 3600 if zero.
+	TTL int
+	// Separator joins the first address and mask length of RFC 2317 zone
+	// names, "/" if empty. Some tools need "-" instead.
+	Separator string
+}
+
+func (o RDNSOptions) withDefaults() RDNSOptions {
+	if len(o.Nameservers) == 0 {
+		o.Nameservers = []string{"ns1.example.com."}
+	}
+	if o.Hostmaster == "" {
+		o.Hostmaster = "hostmaster.example.com."
+	}
+	if o.TTL == 0 {
+		o.TTL = 3600
+	}
+	if o.Separator == "" {
+		o.Separator = "/"
+	}
+	return o
+}
+
+// ReverseZone is an in-addr.arpa zone needed for part of a leaf subnet.
+type ReverseZone struct {
+	// Name is the fully qualified zone name, e.g. "2.0.192.in-addr.arpa.".
+	Name string
+	// Address and MaskLen are the prefix the zone covers.
+	Address uint32
+	MaskLen uint32
+	// Parent is the octet-aligned zone an RFC 2317 classless zone is
+	// delegated from, and empty for octet-aligned zones.
+	Parent string
+}
+
+// reverseName returns the in-addr.arpa name of the first octets of address.
+func reverseName(address uint32, octets int) string {
+	parts := []string{"in-addr.arpa."}
+	for i := 0; i < octets; i++ {
+		parts = append([]string{fmt.Sprint(byte(address >> (24 - 8*i)))}, parts...)
+	}
+	return strings.Join(parts, ".")
+}
+
+// ReverseZones returns the reverse zones for every leaf of the plan. Leaves
+// of /24 or shorter are split into octet-aligned zones; longer prefixes get a
+// classless zone delegated from their /24 as described in RFC 2317.
+func ReverseZones(plan subnet.Plan, opts RDNSOptions) []ReverseZone {
+	opts = opts.withDefaults()
+	var zones []ReverseZone
+	plan.Iterate(func(n *subnet.Subnet) {
+		if n.MaskLen > 24 {
+			parent := reverseName(n.Address, 3)
+			zones = append(zones, ReverseZone{
+				Name:    fmt.Sprintf("%d%s%d.%s", byte(n.Address), opts.Separator, n.MaskLen, parent),
+				Address: n.Address,
+				MaskLen: n.MaskLen,
+				Parent:  parent,
+			})
+			return
+		}
+		boundary := max((n.MaskLen+7)/8*8, 8)
+		step := subnet.SubnetAddresses(boundary)
+		for i := uint32(0); i < 1<<(boundary-n.MaskLen); i++ {
+			address := n.Address + i*step
+			zones = append(zones, ReverseZone{
+				Name:    reverseName(address, int(boundary/8)),
+				Address: address,
+				MaskLen: boundary,
+			})
+		}
+	})
+	return zones
+}
+
+// WriteReverseNames writes one line per reverse zone with the prefix it
+// covers and, for classless zones, the zone it is delegated from.
+func WriteReverseNames(w io.Writer, plan subnet.Plan, opts RDNSOptions) error {
+	var b strings.Builder
+	for _, z := range ReverseZones(plan, opts) {
+		line := z.Name + "\t" + subnet.FormatCIDR(z.Address, z.MaskLen)
+		if z.Parent != "" {
+			line += "\t" + z.Parent
+		}
+		b.WriteString(line + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ZoneStub returns a BIND zone file with the SOA and NS records of z. For
+// classless zones it ends with the records to add to the parent zone, as
+// comments.
+func ZoneStub(z ReverseZone, opts RDNSOptions) string {
+	opts = opts.withDefaults()
+	var b strings.Builder
+	fmt.Fprintf(&b, "; %s\n", subnet.FormatCIDR(z.Address, z.MaskLen))
+	fmt.Fprintf(&b, "$ORIGIN %s\n", z.Name)
+	fmt.Fprintf(&b, "$TTL %d\n", opts.TTL)
+	fmt.Fprintf(&b, "@\tIN\tSOA\t%s %s (\n", opts.Nameservers[0], opts.Hostmaster)
+	b.WriteString("\t\t\t1\t; serial\n")
+	b.WriteString("\t\t\t3600\t; refresh\n")
+	b.WriteString("\t\t\t900\t; retry\n")
+	b.WriteString("\t\t\t604800\t; expire\n")
+	fmt.Fprintf(&b, "\t\t\t%d )\t; minimum\n", opts.TTL)
+	for _, ns := range opts.Nameservers {
+		fmt.Fprintf(&b, "@\tIN\tNS\t%s\n", ns)
+	}
+	if z.Parent != "" {
+		label := strings.TrimSuffix(z.Name, "."+z.Parent)
+		first := byte(z.Address)
+		last := byte(subnet.SubnetLastAddress(z.Address, z.MaskLen))
+		fmt.Fprintf(&b, ";\n; Delegation records for %s:\n", z.Parent)
+		for _, ns := range opts.Nameservers {
+			fmt.Fprintf(&b, "; %s\tIN\tNS\t%s\n", label, ns)
+		}
+		fmt.Fprintf(&b, "; $GENERATE %d-%d $ IN CNAME $.%s\n", first, last, z.Name)
+	}
+	return b.String()
+}
+
+// WriteZoneStubs writes the zone stub of every reverse zone, separated by
+// blank lines.
+func WriteZoneStubs(w io.Writer, plan subnet.Plan, opts RDNSOptions) error {
+	var stubs []string
+	for _, z := range ReverseZones(plan, opts) {
+		stubs = append(stubs, ZoneStub(z, opts))
+	}
+	_, err := io.WriteString(w, strings.Join(stubs, "\n"))
+	return err
+}
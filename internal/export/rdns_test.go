@@ -0,0 +1,58 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+func TestReverseZones(t *testing.T) {
+	root := &subnet.Subnet{Address: subnet.InetAton("192.0.0.0"), MaskLen: 22}
+	root.DivideTo(subnet.InetAton("192.0.2.64"), 26)
+
+	var buf bytes.Buffer
+	if err := WriteReverseNames(&buf, root, RDNSOptions{}); err != nil {
+		t.Fatalf("WriteReverseNames returned error: %v", err)
+	}
+	want := `0.0.192.in-addr.arpa.	192.0.0.0/24
+1.0.192.in-addr.arpa.	192.0.1.0/24
+0/26.2.0.192.in-addr.arpa.	192.0.2.0/26	2.0.192.in-addr.arpa.
+64/26.2.0.192.in-addr.arpa.	192.0.2.64/26	2.0.192.in-addr.arpa.
+128/25.2.0.192.in-addr.arpa.	192.0.2.128/25	2.0.192.in-addr.arpa.
+3.0.192.in-addr.arpa.	192.0.3.0/24
+`
+	if buf.String() != want {
+		t.Errorf("WriteReverseNames wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	wide := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 15}
+	zones := ReverseZones(wide, RDNSOptions{})
+	if len(zones) != 2 || zones[0].Name != "0.10.in-addr.arpa." || zones[1].Name != "1.10.in-addr.arpa." {
+		t.Errorf("ReverseZones(10.0.0.0/15) = %+v; want 0.10 and 1.10", zones)
+	}
+}
+
+func TestZoneStub(t *testing.T) {
+	z := ReverseZone{
+		Name:    "64-26.2.0.192.in-addr.arpa.",
+		Address: subnet.InetAton("192.0.2.64"),
+		MaskLen: 26,
+		Parent:  "2.0.192.in-addr.arpa.",
+	}
+	opts := RDNSOptions{Nameservers: []string{"ns1.example.net.", "ns2.example.net."}, TTL: 300}
+	stub := ZoneStub(z, opts)
+	for _, line := range []string{
+		"$ORIGIN 64-26.2.0.192.in-addr.arpa.\n",
+		"$TTL 300\n",
+		"@\tIN\tSOA\tns1.example.net. hostmaster.example.com. (\n",
+		"@\tIN\tNS\tns2.example.net.\n",
+		"; 64-26\tIN\tNS\tns1.example.net.\n",
+		"; $GENERATE 64-127 $ IN CNAME $.64-26.2.0.192.in-addr.arpa.\n",
+	} {
+		if !strings.Contains(stub, line) {
+			t.Errorf("ZoneStub is missing %q:\n%s", line, stub)
+		}
+	}
+}
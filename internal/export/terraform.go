@@ -0,0 +1,162 @@
+// Package export renders subnet plans in formats consumed by other tools.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// TerraformOptions controls the Terraform exporters.
+type TerraformOptions struct {
+	// Name is the name of the local value or variable, "subnets" if empty.
+	Name string
+	// Variable emits a variable block with a default instead of a locals block.
+	Variable bool
+}
+
+// terraformIdentifier matches the names Terraform accepts for local values
+// and variables.
+var terraformIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// name returns the local value or variable name, checking that it is a
+// valid identifier.
+func (opts TerraformOptions) name() (string, error) {
+	if opts.Name == "" {
+		return "subnets", nil
+	}
+	if !terraformIdentifier.MatchString(opts.Name) {
+		return "", fmt.Errorf("invalid terraform name %q: want a letter or underscore followed by letters, digits, underscores or dashes", opts.Name)
+	}
+	return opts.Name, nil
+}
+
+// terraformType is the Terraform type of the exported map.
+const terraformType = "map(object({ cidr = string, labels = list(string), metadata = map(string) }))"
+
+// terraformSubnet is the value exported for each labelled leaf.
+type terraformSubnet struct {
+	CIDR     string            `json:"cidr"`
+	Labels   []string          `json:"labels"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Name returns the key a leaf is exported under: its "name" metadata if set,
+// otherwise its labels joined with "-". Unlabelled leaves have no name.
+func Name(n *subnet.Subnet) string {
+	if name := n.Metadata["name"]; name != "" {
+		return name
+	}
+	return strings.Join(n.Labels, "-")
+}
+
+// terraformSubnets collects the labelled leaves of the plan keyed by Name.
+func terraformSubnets(plan subnet.Plan) (map[string]terraformSubnet, []string, error) {
+	subnets := make(map[string]terraformSubnet)
+	var names []string
+	var err error
+	plan.Iterate(func(n *subnet.Subnet) {
+		name := Name(n)
+		if name == "" || err != nil {
+			return
+		}
+		if other, ok := subnets[name]; ok {
+			err = fmt.Errorf("%s and %s are both named %q", other.CIDR, n.CIDR(), name)
+			return
+		}
+		labels := n.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		metadata := n.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		subnets[name] = terraformSubnet{CIDR: n.CIDR(), Labels: labels, Metadata: metadata}
+		names = append(names, name)
+	})
+	return subnets, names, err
+}
+
+// WriteTerraformHCL writes the labelled leaves of the plan as an HCL locals
+// or variable block mapping each name to its CIDR, labels and metadata.
+func WriteTerraformHCL(w io.Writer, plan subnet.Plan, opts TerraformOptions) error {
+	subnets, names, err := terraformSubnets(plan)
+	if err != nil {
+		return err
+	}
+	name, err := opts.name()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if opts.Variable {
+		fmt.Fprintf(&b, "variable %s {\n", hclString(name))
+		fmt.Fprintf(&b, "  type = %s\n", terraformType)
+		b.WriteString("  default = {\n")
+	} else {
+		b.WriteString("locals {\n")
+		fmt.Fprintf(&b, "  %s = {\n", name)
+	}
+	for _, key := range names {
+		s := subnets[key]
+		fmt.Fprintf(&b, "    %s = {\n", hclString(key))
+		fmt.Fprintf(&b, "      cidr     = %s\n", hclString(s.CIDR))
+		labels := make([]string, len(s.Labels))
+		for i, l := range s.Labels {
+			labels[i] = hclString(l)
+		}
+		fmt.Fprintf(&b, "      labels   = [%s]\n", strings.Join(labels, ", "))
+		if len(s.Metadata) == 0 {
+			b.WriteString("      metadata = {}\n")
+		} else {
+			b.WriteString("      metadata = {\n")
+			for _, k := range subnet.SortedKeys(s.Metadata) {
+				fmt.Fprintf(&b, "        %s = %s\n", hclString(k), hclString(s.Metadata[k]))
+			}
+			b.WriteString("      }\n")
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("  }\n}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteTerraformJSON writes the same block as WriteTerraformHCL in
+// Terraform's JSON syntax, for use as a ".tf.json" file.
+func WriteTerraformJSON(w io.Writer, plan subnet.Plan, opts TerraformOptions) error {
+	subnets, _, err := terraformSubnets(plan)
+	if err != nil {
+		return err
+	}
+	name, err := opts.name()
+	if err != nil {
+		return err
+	}
+
+	var doc map[string]any
+	if opts.Variable {
+		doc = map[string]any{"variable": map[string]any{
+			name: map[string]any{"type": terraformType, "default": subnets},
+		}}
+	} else {
+		doc = map[string]any{"locals": map[string]any{name: subnets}}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences.
+func hclString(s string) string {
+	q, _ := json.Marshal(s)
+	r := strings.NewReplacer("${", "$${", "%{", "%%{")
+	return r.Replace(string(q))
+}
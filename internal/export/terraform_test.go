@@ -0,0 +1,93 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// testPlan returns 10.0.0.0/22 divided into a labelled web /24, a labelled
+// db /25 with metadata and unlabelled free space.
+func testPlan() *subnet.Subnet {
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 22}
+	web, _ := root.DivideTo(subnet.InetAton("10.0.0.0"), 24)
+	web.Labels = []string{"web", "prod"}
+	db, _ := root.DivideTo(subnet.InetAton("10.0.1.0"), 25)
+	db.Labels = []string{"db"}
+	db.Metadata = map[string]string{"az": "a"}
+	return root
+}
+
+func TestWriteTerraformHCL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTerraformHCL(&buf, testPlan(), TerraformOptions{}); err != nil {
+		t.Fatalf("WriteTerraformHCL returned error: %v", err)
+	}
+	want := `locals {
+  subnets = {
+    "web-prod" = {
+      cidr     = "10.0.0.0/24"
+      labels   = ["web", "prod"]
+      metadata = {}
+    }
+    "db" = {
+      cidr     = "10.0.1.0/25"
+      labels   = ["db"]
+      metadata = {
+        "az" = "a"
+      }
+    }
+  }
+}
+`
+	if buf.String() != want {
+		t.Errorf("WriteTerraformHCL wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteTerraformJSON(t *testing.T) {
+	var buf bytes.Buffer
+	opts := TerraformOptions{Name: "vpc_subnets", Variable: true}
+	if err := WriteTerraformJSON(&buf, testPlan(), opts); err != nil {
+		t.Fatalf("WriteTerraformJSON returned error: %v", err)
+	}
+	var doc struct {
+		Variable map[string]struct {
+			Type    string
+			Default map[string]terraformSubnet
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteTerraformJSON wrote invalid JSON: %v", err)
+	}
+	v, ok := doc.Variable["vpc_subnets"]
+	if !ok {
+		t.Fatalf("WriteTerraformJSON wrote no vpc_subnets variable:\n%s", buf.String())
+	}
+	if len(v.Default) != 2 || v.Default["db"].CIDR != "10.0.1.0/25" || v.Default["db"].Metadata["az"] != "a" {
+		t.Errorf("WriteTerraformJSON default = %+v", v.Default)
+	}
+}
+
+func TestTerraformDuplicateNames(t *testing.T) {
+	root := testPlan()
+	root.Find(subnet.InetAton("10.0.1.128"), 25).Labels = []string{"db"}
+	var buf bytes.Buffer
+	if err := WriteTerraformHCL(&buf, root, TerraformOptions{}); err == nil {
+		t.Errorf("WriteTerraformHCL with duplicate names succeeded; want error")
+	}
+}
+
+func TestTerraformInvalidName(t *testing.T) {
+	for _, name := range []string{"a b", "1st", "net.subnets", `x"`} {
+		var buf bytes.Buffer
+		if err := WriteTerraformHCL(&buf, testPlan(), TerraformOptions{Name: name}); err == nil {
+			t.Errorf("WriteTerraformHCL with name %q succeeded; want error", name)
+		}
+		if err := WriteTerraformJSON(&buf, testPlan(), TerraformOptions{Name: name, Variable: true}); err == nil {
+			t.Errorf("WriteTerraformJSON with name %q succeeded; want error", name)
+		}
+	}
+}
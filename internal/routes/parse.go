@@ -0,0 +1,267 @@
+package routes
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// ParseIPRoute reads the output of "ip route show" (or "ip -4 route"). Local
+// and broadcast routes, as listed for the local table, and IPv6 routes are
+// skipped.
+//
+//	default via 10.0.0.1 dev eth0 proto dhcp metric 100
+//	10.0.0.0/24 dev eth0 proto kernel scope link src 10.0.0.5
+//	blackhole 10.9.0.0/16 proto static
+func ParseIPRoute(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "local" || fields[0] == "broadcast" {
+			continue
+		}
+		switch fields[0] {
+		case "unicast", "blackhole", "unreachable", "prohibit", "throw":
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		route, ok := parsePrefix(fields[0])
+		if !ok {
+			continue
+		}
+		for i := 1; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "via":
+				route.NextHop = fields[i+1]
+			case "dev":
+				route.Interface = fields[i+1]
+			case "proto":
+				route.Protocol = fields[i+1]
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parsePrefix parses "default", a CIDR or a single address as a host route.
+func parsePrefix(s string) (Route, bool) {
+	if s == "default" {
+		return Route{}, true
+	}
+	if address, err := subnet.ParseIP(s); err == nil {
+		return Route{Address: address, MaskLen: 32}, true
+	}
+	address, maskLen, err := subnet.ParseCIDR(s)
+	if err != nil {
+		return Route{}, false
+	}
+	return Route{Address: subnet.NetworkAddress(address, maskLen), MaskLen: maskLen}, true
+}
+
+// ParseNetstat reads the output of "netstat -rn" on Linux, with a Genmask
+// column, and on BSD and macOS, where destinations may be abbreviated. The
+// IPv6 table and link-layer entries are skipped.
+//
+//	0.0.0.0         10.0.0.1        0.0.0.0         UG    0 0          0 eth0
+//	10.0.0.0        0.0.0.0         255.255.255.0   U     0 0          0 eth0
+//	10/8            10.0.0.1           UGSc        en0
+//	192.168.1       link#6             UCS         en0      !
+func ParseNetstat(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "Internet6") {
+			break
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		destination, err := subnet.ParseIP(fields[0])
+		genmask, maskErr := subnet.ParseIP(fields[2])
+		if err == nil && maskErr == nil {
+			maskLen := subnet.MaskLen(genmask)
+			route := Route{Address: subnet.NetworkAddress(destination, maskLen), MaskLen: maskLen}
+			if fields[1] != "0.0.0.0" {
+				route.NextHop = fields[1]
+			}
+			if len(fields) >= 8 {
+				route.Interface = fields[len(fields)-1]
+			}
+			routes = append(routes, route)
+			continue
+		}
+		route, ok := parseBSDDestination(fields[0])
+		if !ok || strings.Count(fields[1], ":") == 5 {
+			continue
+		}
+		if _, err := subnet.ParseIP(fields[1]); err == nil {
+			route.NextHop = fields[1]
+		}
+		if len(fields) >= 4 {
+			route.Interface = fields[3]
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parseBSDDestination parses a BSD netstat destination: "default", or an
+// address with trailing zero octets left out, with an optional mask length
+// that otherwise covers the octets given, as in "10/8" or "192.168.1".
+func parseBSDDestination(s string) (Route, bool) {
+	if s == "default" {
+		return Route{}, true
+	}
+	address, mask, hasMask := strings.Cut(s, "/")
+	octets := strings.Split(address, ".")
+	if len(octets) > 4 {
+		return Route{}, false
+	}
+	maskLen := 8 * len(octets)
+	for len(octets) < 4 {
+		octets = append(octets, "0")
+	}
+	ip, err := subnet.ParseIP(strings.Join(octets, "."))
+	if err != nil {
+		return Route{}, false
+	}
+	if hasMask {
+		n, err := strconv.Atoi(mask)
+		if err != nil || n < 0 || n > 32 {
+			return Route{}, false
+		}
+		maskLen = n
+	}
+	return Route{Address: subnet.NetworkAddress(ip, uint32(maskLen)), MaskLen: uint32(maskLen)}, true
+}
+
+// ciscoCode matches the route codes before a prefix in Cisco output, such as
+// "C", "S*", "O" followed by "IA" or "E2", or "D" followed by "EX".
+var ciscoCode = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]?[*+%]?$|^[*+%]$`)
+
+// ParseShowRoute reads "show ip route" output from Cisco IOS and "show route"
+// output from Juniper Junos. Cisco prefixes listed without a mask under an
+// "is subnetted" heading take the heading's mask, and classful masks
+// otherwise. Local routes to the router's own addresses are skipped, and for
+// routes with several next hops the first is kept.
+//
+//	S*    0.0.0.0/0 [1/0] via 10.0.0.1
+//	C        10.1.1.0/24 is directly connected, GigabitEthernet0/0
+//	O IA     10.2.0.0/16 [110/2] via 10.1.1.2, 00:01:02, GigabitEthernet0/0
+//
+//	10.2.0.0/16        *[OSPF/10] 2d 03:12:11, metric 2
+//	                    >  to 10.1.1.2 via ge-0/0/0.0
+func ParseShowRoute(r io.Reader) ([]Route, error) {
+	var routes []Route
+	var subnetted Route // the last "is subnetted" heading
+	last := -1          // index in routes of the last Junos route, for its next hop
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Junos next hop lines follow their route.
+		if fields[0] == ">" || fields[0] == "to" || fields[0] == "via" {
+			if last >= 0 {
+				for i := 0; i+1 < len(fields); i++ {
+					switch {
+					case fields[i] == "to" && routes[last].NextHop == "":
+						routes[last].NextHop = fields[i+1]
+					case fields[i] == "via" && routes[last].Interface == "":
+						routes[last].Interface = fields[i+1]
+					}
+				}
+			}
+			continue
+		}
+
+		// Junos routes start with the prefix and a bracketed protocol.
+		if len(fields) > 1 && (strings.HasPrefix(fields[1], "*[") || strings.HasPrefix(fields[1], "[")) {
+			last = -1
+			route, ok := parsePrefix(fields[0])
+			if !ok || !strings.Contains(fields[0], "/") {
+				continue
+			}
+			route.Protocol, _, _ = strings.Cut(strings.TrimLeft(fields[1], "*["), "/")
+			if route.Protocol == "Local" {
+				continue
+			}
+			routes = append(routes, route)
+			last = len(routes) - 1
+			continue
+		}
+		last = -1
+
+		// Cisco routes start with their codes, headings with the prefix.
+		i := 0
+		for i < len(fields) && i < 3 && ciscoCode.MatchString(fields[i]) {
+			i++
+		}
+		if i == len(fields) {
+			continue
+		}
+		address, maskLen, err := subnet.ParseCIDR(fields[i])
+		if ip, ipErr := subnet.ParseIP(fields[i]); err != nil && ipErr == nil {
+			address = ip
+			maskLen = classfulMaskLen(address)
+			if subnetted.MaskLen > 0 && subnet.NetworkAddress(subnetted.Address, maskLen) == subnet.NetworkAddress(address, maskLen) {
+				maskLen = subnetted.MaskLen
+			}
+			err = nil
+		}
+		if err != nil {
+			continue
+		}
+		rest := fields[i+1:]
+		if i == 0 {
+			if len(rest) >= 2 && rest[0] == "is" && rest[1] == "subnetted," {
+				subnetted = Route{Address: address, MaskLen: maskLen}
+			}
+			continue
+		}
+		codes := strings.Trim(strings.Join(fields[:i], " "), "*+% ")
+		if codes == "L" {
+			continue
+		}
+		route := Route{Address: subnet.NetworkAddress(address, maskLen), MaskLen: maskLen, Protocol: codes}
+		for j := 0; j+1 < len(rest); j++ {
+			if rest[j] == "via" {
+				route.NextHop = strings.TrimSuffix(rest[j+1], ",")
+				break
+			}
+		}
+		if len(rest) > 1 && strings.HasSuffix(rest[len(rest)-2], ",") {
+			if iface := rest[len(rest)-1]; unicode.IsLetter(rune(iface[0])) {
+				route.Interface = iface
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// classfulMaskLen returns the mask length of the class A, B or C network
+// containing address, or 32 for other classes.
+func classfulMaskLen(address uint32) uint32 {
+	switch {
+	case address < 0x80000000:
+		return 8
+	case address < 0xc0000000:
+		return 16
+	case address < 0xe0000000:
+		return 24
+	}
+	return 32
+}
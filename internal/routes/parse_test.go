@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describe formats routes for comparison in tests.
+func describe(routes []Route) string {
+	var lines []string
+	for _, r := range routes {
+		line := fmt.Sprintf("%s %s %s %s", r.CIDR(), r.NextHop, r.Interface, r.Protocol)
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParseIPRoute(t *testing.T) {
+	input := `default via 10.0.0.1 dev eth0 proto dhcp metric 100
+10.0.0.0/24 dev eth0 proto kernel scope link src 10.0.0.5
+10.8.0.0/16 via 10.0.0.254 dev eth0
+blackhole 10.9.0.0/16 proto static
+192.168.7.7 via 10.0.0.2 dev eth0
+local 10.0.0.5 dev eth0 proto kernel scope host src 10.0.0.5
+broadcast 10.0.0.255 dev eth0 proto kernel scope link src 10.0.0.5
+fe80::/64 dev eth0 proto kernel metric 256
+`
+	got, err := ParseIPRoute(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseIPRoute returned error: %v", err)
+	}
+	want := `0.0.0.0/0 10.0.0.1 eth0 dhcp
+10.0.0.0/24  eth0 kernel
+10.8.0.0/16 10.0.0.254 eth0
+10.9.0.0/16   static
+192.168.7.7/32 10.0.0.2 eth0`
+	if describe(got) != want {
+		t.Errorf("ParseIPRoute =\n%s\nwant\n%s", describe(got), want)
+	}
+}
+
+func TestParseNetstat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "linux",
+			input: `Kernel IP routing table
+Destination     Gateway         Genmask         Flags   MSS Window  irtt Iface
+0.0.0.0         10.0.0.1        0.0.0.0         UG        0 0          0 eth0
+10.0.0.0        0.0.0.0         255.255.255.0   U         0 0          0 eth0
+10.8.0.0        10.0.0.254      255.255.0.0     UG        0 0          0 eth0
+`,
+			want: `0.0.0.0/0 10.0.0.1 eth0
+10.0.0.0/24  eth0
+10.8.0.0/16 10.0.0.254 eth0`,
+		},
+		{
+			name: "bsd",
+			input: `Routing tables
+
+Internet:
+Destination        Gateway            Flags        Netif Expire
+default            192.168.1.1        UGScg          en0
+10/8               192.168.1.254      UGSc           en0
+127                127.0.0.1          UCS            lo0
+192.168.1          link#6             UCS            en0      !
+192.168.1.1/32     link#6             UCS            en0      !
+192.168.1.23       a4:83:e7:12:34:56  UHLWIi         en0   1180
+
+Internet6:
+Destination                             Gateway                         Flags         Netif Expire
+default                                 fe80::1%en0                     UGcg            en0
+`,
+			want: `0.0.0.0/0 192.168.1.1 en0
+10.0.0.0/8 192.168.1.254 en0
+127.0.0.0/8 127.0.0.1 lo0
+192.168.1.0/24  en0
+192.168.1.1/32  en0`,
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseNetstat(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("%s: ParseNetstat returned error: %v", test.name, err)
+		}
+		if describe(got) != test.want {
+			t.Errorf("%s: ParseNetstat =\n%s\nwant\n%s", test.name, describe(got), test.want)
+		}
+	}
+}
+
+func TestParseShowRoute(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "cisco",
+			input: `Codes: L - local, C - connected, S - static, R - RIP, M - mobile, B - BGP
+       D - EIGRP, EX - EIGRP external, O - OSPF, IA - OSPF inter area
+       ia - IS-IS inter area, * - candidate default, U - per-user static route
+
+Gateway of last resort is 10.1.1.254 to network 0.0.0.0
+
+S*    0.0.0.0/0 [1/0] via 10.1.1.254
+      10.0.0.0/8 is variably subnetted, 4 subnets, 3 masks
+C        10.1.1.0/24 is directly connected, GigabitEthernet0/0
+L        10.1.1.1/32 is directly connected, GigabitEthernet0/0
+O IA     10.2.0.0/16 [110/2] via 10.1.1.2, 00:01:02, GigabitEthernet0/0
+                     [110/2] via 10.1.1.3, 00:01:02, GigabitEthernet0/0
+D EX     10.3.0.0/16 [170/2] via 10.1.1.4, 2w3d, GigabitEthernet0/1
+      172.16.0.0/24 is subnetted, 2 subnets
+C        172.16.0.0 is directly connected, Loopback0
+S        172.16.5.0 [1/0] via 10.1.1.5
+S     192.168.9.0 [1/0] via 10.1.1.6
+`,
+			want: `0.0.0.0/0 10.1.1.254  S
+10.1.1.0/24  GigabitEthernet0/0 C
+10.2.0.0/16 10.1.1.2 GigabitEthernet0/0 O IA
+10.3.0.0/16 10.1.1.4 GigabitEthernet0/1 D EX
+172.16.0.0/24  Loopback0 C
+172.16.5.0/24 10.1.1.5  S
+192.168.9.0/24 10.1.1.6  S`,
+		},
+		{
+			name: "junos",
+			input: `inet.0: 5 destinations, 6 routes (5 active, 0 holddown, 0 hidden)
++ = Active Route, - = Last Active, * = Both
+
+0.0.0.0/0          *[Static/5] 2d 03:12:11
+                    >  to 10.1.1.254 via ge-0/0/0.0
+10.1.1.0/24        *[Direct/0] 2d 03:12:11
+                    >  via ge-0/0/0.0
+10.1.1.1/32        *[Local/0] 2d 03:12:11
+                       Local via ge-0/0/0.0
+10.2.0.0/16        *[OSPF/10] 1d 00:00:05, metric 2
+                    >  to 10.1.1.2 via ge-0/0/0.0
+                       to 10.1.1.3 via ge-0/0/1.0
+                    [Static/200] 5d 00:00:00
+                    >  to 10.1.1.9 via ge-0/0/0.0
+`,
+			want: `0.0.0.0/0 10.1.1.254 ge-0/0/0.0 Static
+10.1.1.0/24  ge-0/0/0.0 Direct
+10.2.0.0/16 10.1.1.2 ge-0/0/0.0 OSPF`,
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseShowRoute(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("%s: ParseShowRoute returned error: %v", test.name, err)
+		}
+		if describe(got) != test.want {
+			t.Errorf("%s: ParseShowRoute =\n%s\nwant\n%s", test.name, describe(got), test.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package routes reads the prefixes of saved routing tables and uses them to
+// seed a plan or to check one against the network.
+package routes
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// Route is a prefix of a routing table and how it is reached.
+type Route struct {
+	Address   uint32
+	MaskLen   uint32
+	NextHop   string
+	Interface string
+	Protocol  string
+}
+
+// CIDR returns the route's prefix in "10.0.0.0/24" notation.
+func (r Route) CIDR() string {
+	return subnet.FormatCIDR(r.Address, r.MaskLen)
+}
+
+func (r Route) String() string {
+	s := r.CIDR()
+	if r.NextHop != "" {
+		s += " via " + r.NextHop
+	}
+	if r.Interface != "" {
+		s += " dev " + r.Interface
+	}
+	return s
+}
+
+// Metadata returns the route's next hop, interface and protocol as plan
+// metadata under "via", "dev" and "proto", or nil if none is known.
+func (r Route) Metadata() map[string]string {
+	var metadata map[string]string
+	for _, kv := range [][2]string{{"via", r.NextHop}, {"dev", r.Interface}, {"proto", r.Protocol}} {
+		if kv[1] == "" {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[kv[0]] = kv[1]
+	}
+	return metadata
+}
+
+// isDefault reports whether r is a default route.
+func (r Route) isDefault() bool {
+	return r.MaskLen == 0
+}
+
+// Build returns a plan holding every route. Routes not within another become
+// roots, which are divided down to each more specific route, and the node of
+// each route gets its Metadata, even where it is an aggregate that ends up
+// divided; flat plans keep that on a line before the subnets inside it. The
+// default route is left out, as it would
+// make all address space a single root; of several routes to one prefix the
+// first is kept.
+func Build(routes []Route) (*subnet.Workspace, error) {
+	sorted := slices.Clone(routes)
+	slices.SortStableFunc(sorted, func(a, b Route) int {
+		if a.MaskLen != b.MaskLen {
+			return cmp.Compare(a.MaskLen, b.MaskLen)
+		}
+		return cmp.Compare(a.Address, b.Address)
+	})
+	ws := &subnet.Workspace{}
+	seen := make(map[string]bool)
+	for _, r := range sorted {
+		if r.isDefault() || seen[r.CIDR()] {
+			continue
+		}
+		seen[r.CIDR()] = true
+		if ws.Root(r.Address, r.MaskLen) == nil {
+			if _, err := ws.Add(r.Address, r.MaskLen); err != nil {
+				return nil, err
+			}
+		}
+		n, err := ws.DivideTo(r.Address, r.MaskLen)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r, err)
+		}
+		n.Metadata = r.Metadata()
+	}
+	return ws, nil
+}
+
+// Finding is a route that is missing from or conflicts with a plan.
+type Finding struct {
+	Route Route
+	// Subnet is the planned node the route falls in, or nil if it is
+	// outside the plan.
+	Subnet  *subnet.Subnet
+	Problem string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Route, f.Problem)
+}
+
+// Check compares routes with a plan. A route is missing from the plan when
+// it is outside every root or only covers free space, and it conflicts with
+// the plan when its planned subnet records a different "via" or "dev". Routes
+// to allocated subnets, to aggregates of them and within them are fine. The
+// default route is not checked.
+func Check(plan subnet.Plan, routes []Route) []Finding {
+	var findings []Finding
+	for _, r := range routes {
+		if r.isDefault() {
+			continue
+		}
+		n := plan.Locate(r.Address, r.MaskLen)
+		switch {
+		case n == nil:
+			findings = append(findings, Finding{r, nil, "outside the plan"})
+		case isFree(n):
+			problem := "unallocated in the plan"
+			if n.MaskLen < r.MaskLen {
+				problem = "within unallocated " + n.CIDR()
+			}
+			findings = append(findings, Finding{r, n, problem})
+		case n.MaskLen == r.MaskLen:
+			metadata := r.Metadata()
+			for _, k := range []string{"via", "dev"} {
+				planned, ok := n.Metadata[k]
+				if ok && metadata[k] != "" && planned != metadata[k] {
+					problem := fmt.Sprintf("planned %s %s, routed %s %s", k, planned, k, metadata[k])
+					findings = append(findings, Finding{r, n, problem})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// isFree reports whether nothing is planned in n.
+func isFree(n *subnet.Subnet) bool {
+	blocks := n.FreeBlocks()
+	return len(blocks) == 1 && blocks[0] == n
+}
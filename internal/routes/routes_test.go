@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+func TestBuild(t *testing.T) {
+	input := `default via 10.0.0.1 dev eth0
+10.0.0.0/24 dev eth0 proto kernel
+10.0.0.0/16 via 10.0.0.254 dev eth0
+10.0.3.0/24 via 10.0.0.253 dev eth0
+10.0.3.0/24 via 10.0.0.252 dev eth0
+192.168.7.0/24 via 10.0.0.2 dev eth0
+`
+	routes, err := ParseIPRoute(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseIPRoute returned error: %v", err)
+	}
+	ws, err := Build(routes)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := subnet.WriteFlatWorkspace(&buf, ws); err != nil {
+		t.Fatalf("WriteFlatWorkspace returned error: %v", err)
+	}
+	want := `root 10.0.0.0/16
+10.0.0.0/16 dev=eth0 via=10.0.0.254
+10.0.0.0/24 dev=eth0 proto=kernel
+10.0.1.0/24
+10.0.2.0/24
+10.0.3.0/24 dev=eth0 via=10.0.0.253
+10.0.4.0/22
+10.0.8.0/21
+10.0.16.0/20
+10.0.32.0/19
+10.0.64.0/18
+10.0.128.0/17
+root 192.168.7.0/24
+192.168.7.0/24 dev=eth0 via=10.0.0.2
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Build =\n%s\nwant\n%s", got, want)
+	}
+
+	// The metadata of aggregate routes such as 10.0.0.0/16 survives saving.
+	for _, filename := range []string{"routes.plan", "routes.json"} {
+		data, err := subnet.MarshalWorkspace(ws, filename)
+		if err != nil {
+			t.Fatalf("MarshalWorkspace(%s) returned error: %v", filename, err)
+		}
+		loaded, err := subnet.UnmarshalWorkspace(data, filename)
+		if err != nil {
+			t.Fatalf("UnmarshalWorkspace(%s) returned error: %v", filename, err)
+		}
+		if changes := subnet.DiffWorkspaces(ws, loaded); len(changes) != 0 {
+			t.Errorf("%s round trip changed %v", filename, changes)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	plan := `root 10.0.0.0/16
+10.0.0.0/24 web dev=eth0
+10.0.1.0/24 db via=10.0.0.9
+10.0.2.0/23
+10.0.4.0/22
+10.0.8.0/21 office
+10.0.16.0/20
+10.0.32.0/19
+10.0.64.0/18
+10.0.128.0/17
+`
+	ws, err := subnet.ReadFlatWorkspace(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+	}
+	input := `default via 10.0.0.1 dev eth0
+10.0.0.0/24 dev eth0
+10.0.1.0/24 via 10.0.0.8 dev eth0
+10.0.2.0/24 via 10.0.0.10 dev eth0
+10.0.4.0/22 via 10.0.0.10 dev eth0
+10.0.9.0/24 via 10.0.0.11 dev eth0
+10.0.0.0/16 via 10.0.0.12 dev eth0
+172.16.0.0/12 via 10.0.0.13 dev eth0
+`
+	routes, err := ParseIPRoute(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseIPRoute returned error: %v", err)
+	}
+	var got []string
+	for _, f := range Check(ws, routes) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		"10.0.1.0/24 via 10.0.0.8 dev eth0: planned via 10.0.0.9, routed via 10.0.0.8",
+		"10.0.2.0/24 via 10.0.0.10 dev eth0: within unallocated 10.0.2.0/23",
+		"10.0.4.0/22 via 10.0.0.10 dev eth0: unallocated in the plan",
+		"172.16.0.0/12 via 10.0.0.13 dev eth0: outside the plan",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Check =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
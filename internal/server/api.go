@@ -0,0 +1,419 @@
+package server
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rochana-atapattu/subnets/internal/store"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// apiSubnet is the JSON representation of a node returned by the API.
+type apiSubnet struct {
+	CIDR        string            `json:"cidr"`
+	Netmask     string            `json:"netmask"`
+	FirstUsable string            `json:"first_usable"`
+	LastUsable  string            `json:"last_usable"`
+	Broadcast   string            `json:"broadcast"`
+	Hosts       uint32            `json:"hosts"`
+	Labels      []string          `json:"labels"`
+	Metadata    map[string]string `json:"metadata"`
+	// Assignments maps the assigned addresses of a leaf to their hosts.
+	Assignments map[string]subnet.Host `json:"assignments,omitempty"`
+	Children    []apiSubnet            `json:"children,omitempty"`
+}
+
+// apiHost is an address assigned to a host.
+type apiHost struct {
+	Address string `json:"address"`
+	subnet.Host
+}
+
+func newAPISubnet(n *subnet.Subnet) apiSubnet {
+	first, last := subnet.UsableRange(n.Address, n.MaskLen)
+	s := apiSubnet{
+		CIDR:        n.CIDR(),
+		Netmask:     subnet.InetNtoa(subnet.SubnetNetmask(n.MaskLen)),
+		FirstUsable: subnet.InetNtoa(first),
+		LastUsable:  subnet.InetNtoa(last),
+		Broadcast:   subnet.InetNtoa(subnet.SubnetLastAddress(n.Address, n.MaskLen)),
+		Hosts:       subnet.UsableHosts(n.MaskLen),
+		Labels:      n.Labels,
+		Metadata:    n.Metadata,
+		Assignments: n.Hosts,
+	}
+	if s.Labels == nil {
+		s.Labels = []string{}
+	}
+	if s.Metadata == nil {
+		s.Metadata = map[string]string{}
+	}
+	for _, child := range []*subnet.Subnet{n.Left, n.Right} {
+		if child != nil {
+			s.Children = append(s.Children, newAPISubnet(child))
+		}
+	}
+	return s
+}
+
+// apiError is the body of every API error response.
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// errorCodes maps HTTP statuses to the API's error codes.
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusNotFound:            "not_found",
+	http.StatusMethodNotAllowed:    "method_not_allowed",
+	http.StatusConflict:            "conflict",
+	http.StatusPreconditionFailed:  "precondition_failed",
+	http.StatusUnprocessableEntity: "unprocessable",
+	http.StatusInternalServerError: "internal",
+}
+
+// errorHandler writes API errors as apiError bodies and leaves the web pages
+// to echo's default handler.
+func errorHandler(e *echo.Echo) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if !strings.HasPrefix(c.Request().URL.Path, "/api/") {
+			e.DefaultHTTPErrorHandler(err, c)
+			return
+		}
+		status, message := http.StatusInternalServerError, err.Error()
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			status, message = he.Code, fmt.Sprint(he.Message)
+		}
+		var body apiError
+		body.Error.Code = errorCodes[status]
+		if body.Error.Code == "" {
+			body.Error.Code = strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+		}
+		body.Error.Message = message
+		if !c.Response().Committed {
+			c.JSON(status, body)
+		}
+	}
+}
+
+// registerAPI adds the version 1 JSON API routes.
+func (s *Server) registerAPI(e *echo.Echo) {
+	e.HTTPErrorHandler = errorHandler(e)
+	g := e.Group("/api/v1")
+	g.GET("/openapi.yaml", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, "application/yaml", openAPI)
+	})
+	g.GET("/tree", s.apiTree)
+	g.GET("/roots", s.apiRoots)
+	g.GET("/subnets/:address/:mask", s.apiGet)
+	g.POST("/subnets/:address/:mask/divide", s.apiDivide)
+	g.POST("/subnets/:address/:mask/join", s.apiJoin)
+	g.PUT("/subnets/:address/:mask/labels", s.apiLabels)
+	g.PUT("/subnets/:address/:mask/metadata", s.apiMetadata)
+	g.POST("/subnets/:address/:mask/hosts", s.apiAssignHost)
+	g.DELETE("/subnets/:address/:mask/hosts/:ip", s.apiRemoveHost)
+	g.POST("/allocate", s.apiAllocate)
+	g.GET("/lookup/:ip", s.apiLookup)
+}
+
+// cidr returns the prefix named by the :address and :mask path parameters.
+func cidr(c echo.Context) (uint32, uint32, error) {
+	address, maskLen, err := subnet.ParseCIDR(c.Param("address") + "/" + c.Param("mask"))
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return address, maskLen, nil
+}
+
+// find returns the node for a prefix or a not found error.
+func find(ws *subnet.Workspace, address, maskLen uint32) (*subnet.Subnet, error) {
+	n := ws.Find(address, maskLen)
+	if n == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("%s is not in the plan", subnet.FormatCIDR(address, maskLen)))
+	}
+	return n, nil
+}
+
+// respond writes a body with the plan version as its ETag.
+func respond(c echo.Context, status int, version uint64, body any) error {
+	c.Response().Header().Set("ETag", store.ETag(version))
+	return c.JSON(status, body)
+}
+
+// apiUpdate applies f to the plan if the If-Match header, when present,
+// names the current version, and responds with the node f returns.
+func (s *Server) apiUpdate(c echo.Context, status int, f func(ws *subnet.Workspace) (*subnet.Subnet, error)) error {
+	ifMatch, err := store.ParseETag(c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, "If-Match does not name a version of the plan")
+	}
+	var result apiSubnet
+	version, err := s.update(ifMatch, func(ws *subnet.Workspace) error {
+		n, err := f(ws)
+		if err == nil {
+			result = newAPISubnet(n)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return respond(c, status, version, result)
+}
+
+// apiEdit applies f to the node named in the path through apiUpdate.
+func (s *Server) apiEdit(c echo.Context, f func(n *subnet.Subnet) error) error {
+	address, maskLen, err := cidr(c)
+	if err != nil {
+		return err
+	}
+	return s.apiUpdate(c, http.StatusOK, func(ws *subnet.Workspace) (*subnet.Subnet, error) {
+		n, err := find(ws, address, maskLen)
+		if err != nil {
+			return nil, err
+		}
+		return n, f(n)
+	})
+}
+
+// apiTree returns the tree of a plan with a single root. Plans with several
+// roots are listed by apiRoots.
+func (s *Server) apiTree(c echo.Context) error {
+	var result apiSubnet
+	var err error
+	version := s.store.View(func(ws *subnet.Workspace) {
+		var root *subnet.Subnet
+		if root, err = ws.Single(); err == nil {
+			result = newAPISubnet(root)
+		}
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err.Error()+"; use /api/v1/roots")
+	}
+	return respond(c, http.StatusOK, version, result)
+}
+
+func (s *Server) apiRoots(c echo.Context) error {
+	var result []apiSubnet
+	version := s.store.View(func(ws *subnet.Workspace) {
+		for _, root := range ws.Roots {
+			result = append(result, newAPISubnet(root))
+		}
+	})
+	return respond(c, http.StatusOK, version, result)
+}
+
+func (s *Server) apiGet(c echo.Context) error {
+	address, maskLen, err := cidr(c)
+	if err != nil {
+		return err
+	}
+	var result apiSubnet
+	version := s.store.View(func(ws *subnet.Workspace) {
+		var n *subnet.Subnet
+		if n, err = find(ws, address, maskLen); err == nil {
+			result = newAPISubnet(n)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return respond(c, http.StatusOK, version, result)
+}
+
+func (s *Server) apiDivide(c echo.Context) error {
+	return s.apiEdit(c, divideSubnet)
+}
+
+func (s *Server) apiJoin(c echo.Context) error {
+	return s.apiEdit(c, joinSubnet)
+}
+
+// divideSubnet divides n, or refuses with 409 if n.CanDivide does.
+func divideSubnet(n *subnet.Subnet) error {
+	if err := n.CanDivide(); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	n.Divide()
+	return nil
+}
+
+// joinSubnet joins n, or refuses with 409 if n.CanJoin does.
+func joinSubnet(n *subnet.Subnet) error {
+	if err := n.CanJoin(); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	n.Join()
+	return nil
+}
+
+func (s *Server) apiLabels(c echo.Context) error {
+	var body struct {
+		Labels []string `json:"labels"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	return s.apiEdit(c, func(n *subnet.Subnet) error {
+		if err := leafOnly(n, len(body.Labels) > 0); err != nil {
+			return err
+		}
+		n.Labels = body.Labels
+		if len(n.Labels) == 0 {
+			n.Labels = nil
+		}
+		return nil
+	})
+}
+
+func (s *Server) apiMetadata(c echo.Context) error {
+	var body struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	if err := subnet.CheckMetadata(body.Metadata); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return s.apiEdit(c, func(n *subnet.Subnet) error {
+		if err := leafOnly(n, len(body.Metadata) > 0); err != nil {
+			return err
+		}
+		n.Metadata = body.Metadata
+		if len(n.Metadata) == 0 {
+			n.Metadata = nil
+		}
+		return nil
+	})
+}
+
+// leafOnly refuses to set attributes on a divided subnet, which only its
+// leaves carry. Clearing them is allowed.
+func leafOnly(n *subnet.Subnet, setting bool) error {
+	if setting && (n.Left != nil || n.Right != nil) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("%s is divided; set attributes on its leaves", n.CIDR()))
+	}
+	return nil
+}
+
+func (s *Server) apiAssignHost(c echo.Context) error {
+	var body apiHost
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	address, maskLen, err := cidr(c)
+	if err != nil {
+		return err
+	}
+	ifMatch, err := store.ParseETag(c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, "If-Match does not name a version of the plan")
+	}
+	version, err := s.update(ifMatch, func(ws *subnet.Workspace) error {
+		n, err := find(ws, address, maskLen)
+		if err != nil {
+			return err
+		}
+		if body.Address == "" {
+			if body.Address, err = n.NextFreeHost(); err != nil {
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
+			}
+		} else if _, ok := n.Hosts[body.Address]; ok {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("%s is already assigned", body.Address))
+		}
+		if err := n.SetHost(body.Address, body.Host); err != nil {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return respond(c, http.StatusCreated, version, body)
+}
+
+func (s *Server) apiRemoveHost(c echo.Context) error {
+	return s.apiEdit(c, func(n *subnet.Subnet) error {
+		if err := n.RemoveHost(c.Param("ip")); err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return nil
+	})
+}
+
+func (s *Server) apiAllocate(c echo.Context) error {
+	var body struct {
+		Prefix   *uint32           `json:"prefix"`
+		Within   string            `json:"within"`
+		Labels   []string          `json:"labels"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	if body.Prefix == nil || *body.Prefix > 32 {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "prefix must be a mask length between 0 and 32")
+	}
+	if len(body.Labels) == 0 && len(body.Metadata) == 0 {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "labels or metadata are required to mark the subnet allocated")
+	}
+	if err := subnet.CheckMetadata(body.Metadata); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var withinAddress, withinMaskLen uint32
+	if body.Within != "" {
+		var err error
+		if withinAddress, withinMaskLen, err = subnet.ParseCIDR(body.Within); err != nil {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+	}
+
+	return s.apiUpdate(c, http.StatusCreated, func(ws *subnet.Workspace) (*subnet.Subnet, error) {
+		allocate := ws.Allocate
+		if body.Within != "" {
+			parent, err := find(ws, withinAddress, withinMaskLen)
+			if err != nil {
+				return nil, err
+			}
+			allocate = parent.Allocate
+		}
+		n, err := allocate(*body.Prefix)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		n.Labels = body.Labels
+		n.Metadata = body.Metadata
+		return n, nil
+	})
+}
+
+func (s *Server) apiLookup(c echo.Context) error {
+	ip := c.Param("ip")
+	address, err := subnet.ParseIP(ip)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid IP address %q", ip))
+	}
+	var result *apiSubnet
+	version := s.store.View(func(ws *subnet.Workspace) {
+		if n := ws.Locate(address, 32); n != nil {
+			r := newAPISubnet(n)
+			result = &r
+		}
+	})
+	if result == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("%s is not in the plan", ip))
+	}
+	return respond(c, http.StatusOK, version, *result)
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// call sends a JSON request to the handler and decodes the response body.
+func call(t *testing.T, h http.Handler, method, path, body string, out any) int {
+	t.Helper()
+	return callIfMatch(t, h, method, path, "", body, out)
+}
+
+// callIfMatch is call with an If-Match header.
+func callIfMatch(t *testing.T, h http.Handler, method, path, ifMatch, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s returned invalid JSON %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAPI(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.json")
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 22}
+	h := newHandler(t, file, root)
+
+	var s apiSubnet
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/22/divide", "", &s); code != http.StatusOK || len(s.Children) != 2 {
+		t.Fatalf("divide = %d %+v; want 200 with two children", code, s)
+	}
+
+	body := `{"prefix": 24, "labels": ["web"], "metadata": {"env": "prod"}}`
+	if code := call(t, h, "POST", "/api/v1/allocate", body, &s); code != http.StatusCreated || s.CIDR != "10.0.0.0/24" {
+		t.Fatalf("allocate = %d %+v; want 201 10.0.0.0/24", code, s)
+	}
+	body = `{"prefix": 23, "within": "10.0.2.0/23", "labels": ["db"]}`
+	if code := call(t, h, "POST", "/api/v1/allocate", body, &s); code != http.StatusCreated || s.CIDR != "10.0.2.0/23" {
+		t.Fatalf("allocate within = %d %+v; want 201 10.0.2.0/23", code, s)
+	}
+
+	for _, body := range []string{`{"prefix": 26, "metadata": {"": "x"}}`, `{"prefix": 26, "metadata": {"used": "3"}}`} {
+		var e apiError
+		if code := call(t, h, "POST", "/api/v1/allocate", body, &e); code != http.StatusBadRequest {
+			t.Errorf("allocate %s = %d %+v; want 400", body, code, e)
+		}
+		body = strings.Replace(body, `"prefix": 26, `, "", 1)
+		if code := call(t, h, "PUT", "/api/v1/subnets/10.0.0.0/24/metadata", body, &e); code != http.StatusBadRequest {
+			t.Errorf("metadata %s = %d %+v; want 400", body, code, e)
+		}
+	}
+
+	if code := call(t, h, "GET", "/api/v1/lookup/10.0.0.77", "", &s); code != http.StatusOK || s.CIDR != "10.0.0.0/24" || s.Metadata["env"] != "prod" {
+		t.Errorf("lookup = %d %+v; want 200 10.0.0.0/24", code, s)
+	}
+
+	if code := call(t, h, "PUT", "/api/v1/subnets/10.0.2.0/23/labels", `{"labels": ["db", "replica"]}`, &s); code != http.StatusOK || len(s.Labels) != 2 {
+		t.Errorf("set labels = %d %+v", code, s)
+	}
+	if code := call(t, h, "PUT", "/api/v1/subnets/10.0.2.0/23/metadata", `{"metadata": {"az": "b"}}`, &s); code != http.StatusOK || s.Metadata["az"] != "b" {
+		t.Errorf("set metadata = %d %+v", code, s)
+	}
+	saved, err := subnet.LoadWorkspace(file)
+	if err != nil || saved.Find(subnet.InetAton("10.0.2.0"), 23).Metadata["az"] != "b" {
+		t.Errorf("plan file not updated: %v", err)
+	}
+
+	if code := call(t, h, "GET", "/api/v1/tree", "", &s); code != http.StatusOK || s.CIDR != "10.0.0.0/22" {
+		t.Errorf("tree = %d %+v", code, s)
+	}
+	// Joins and labels that would drop allocations are refused.
+	var e apiError
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/22/join", "", &e); code != http.StatusConflict {
+		t.Errorf("join over allocated subnets = %d %+v; want 409", code, e)
+	}
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/23/join", "", &e); code != http.StatusConflict {
+		t.Errorf("join over a labelled leaf = %d %+v; want 409", code, e)
+	}
+	if code := call(t, h, "PUT", "/api/v1/subnets/10.0.0.0/23/labels", `{"labels": ["x"]}`, &e); code != http.StatusConflict {
+		t.Errorf("labels on a divided subnet = %d %+v; want 409", code, e)
+	}
+	if code := call(t, h, "PUT", "/api/v1/subnets/10.0.0.0/23/metadata", `{"metadata": {"az": "a"}}`, &e); code != http.StatusConflict {
+		t.Errorf("metadata on a divided subnet = %d %+v; want 409", code, e)
+	}
+
+	call(t, h, "PUT", "/api/v1/subnets/10.0.0.0/24/labels", `{"labels": []}`, &s)
+	call(t, h, "PUT", "/api/v1/subnets/10.0.0.0/24/metadata", `{"metadata": {}}`, &s)
+	var joined apiSubnet
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/23/join", "", &joined); code != http.StatusOK || len(joined.Children) != 0 {
+		t.Errorf("join = %d %+v", code, joined)
+	}
+}
+
+func TestAPIIfMatch(t *testing.T) {
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 24}
+	h := newHandler(t, filepath.Join(t.TempDir(), "plan.json"), root)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/tree", nil))
+	etag := rec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("GET /api/v1/tree ETag = %q; want \"1\"", etag)
+	}
+
+	var s apiSubnet
+	if code := callIfMatch(t, h, "POST", "/api/v1/subnets/10.0.0.0/24/divide", etag, "", &s); code != http.StatusOK {
+		t.Fatalf("divide with current ETag = %d; want 200", code)
+	}
+	var e apiError
+	if code := callIfMatch(t, h, "PUT", "/api/v1/subnets/10.0.0.0/25/labels", etag, `{"labels": ["x"]}`, &e); code != http.StatusPreconditionFailed || e.Error.Code != "precondition_failed" {
+		t.Errorf("labels with stale ETag = %d %+v; want 412", code, e)
+	}
+	if code := callIfMatch(t, h, "PUT", "/api/v1/subnets/10.0.0.0/25/labels", `"2"`, `{"labels": ["x"]}`, &s); code != http.StatusOK {
+		t.Errorf("labels with current ETag = %d; want 200", code)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 24}
+	h := newHandler(t, filepath.Join(t.TempDir(), "plan.json"), root)
+
+	testCases := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"GET", "/api/v1/subnets/10.9.0.0/24", "", http.StatusNotFound, "not_found"},
+		{"GET", "/api/v1/subnets/10.0.0.0/99", "", http.StatusBadRequest, "bad_request"},
+		{"POST", "/api/v1/subnets/10.0.0.0/24/join", "", http.StatusConflict, "conflict"},
+		{"POST", "/api/v1/allocate", `{"labels": ["x"]}`, http.StatusUnprocessableEntity, "unprocessable"},
+		{"POST", "/api/v1/allocate", `{"prefix": 24}`, http.StatusUnprocessableEntity, "unprocessable"},
+		{"POST", "/api/v1/allocate", `{"prefix": 16, "labels": ["x"]}`, http.StatusConflict, "conflict"},
+		{"POST", "/api/v1/allocate", `{"prefix": `, http.StatusBadRequest, "bad_request"},
+		{"GET", "/api/v1/lookup/10.1.0.1", "", http.StatusNotFound, "not_found"},
+		{"GET", "/api/v1/lookup/bogus", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/api/v1/lookup/010.0.0.1", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/api/v1/nothing", "", http.StatusNotFound, "not_found"},
+	}
+	for _, tc := range testCases {
+		var e apiError
+		if status := call(t, h, tc.method, tc.path, tc.body, &e); status != tc.status || e.Error.Code != tc.code || e.Error.Message == "" {
+			t.Errorf("%s %s = %d %+v; want %d %s", tc.method, tc.path, status, e, tc.status, tc.code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/openapi.yaml", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "openapi: 3") {
+		t.Errorf("openapi.yaml = %d %q", rec.Code, rec.Body.String()[:20])
+	}
+}
+
+func TestAPIRoots(t *testing.T) {
+	h := newHandler(t, filepath.Join(t.TempDir(), "plan.json"),
+		&subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 16},
+		&subnet.Subnet{Address: subnet.InetAton("192.168.0.0"), MaskLen: 24},
+	)
+
+	var e apiError
+	if code := call(t, h, "GET", "/api/v1/tree", "", &e); code != http.StatusConflict {
+		t.Errorf("tree with two roots = %d %+v; want 409", code, e)
+	}
+	var roots []apiSubnet
+	if code := call(t, h, "GET", "/api/v1/roots", "", &roots); code != http.StatusOK || len(roots) != 2 || roots[1].CIDR != "192.168.0.0/24" {
+		t.Errorf("roots = %d %+v", code, roots)
+	}
+
+	var s apiSubnet
+	if code := call(t, h, "POST", "/api/v1/allocate", `{"prefix": 25, "labels": ["lab"]}`, &s); code != http.StatusCreated || s.CIDR != "192.168.0.0/25" {
+		t.Errorf("allocate = %d %+v; want the best fit 192.168.0.0/25", code, s)
+	}
+	if code := call(t, h, "GET", "/api/v1/lookup/192.168.0.9", "", &s); code != http.StatusOK || s.CIDR != "192.168.0.0/25" {
+		t.Errorf("lookup = %d %+v; want 192.168.0.0/25", code, s)
+	}
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/16/divide", "", &s); code != http.StatusOK {
+		t.Errorf("divide in the first root = %d", code)
+	}
+}
+
+func TestAPIHosts(t *testing.T) {
+	h := newHandler(t, filepath.Join(t.TempDir(), "plan.json"), &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 30})
+
+	var host apiHost
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/30/hosts", `{"hostname": "gw"}`, &host); code != http.StatusCreated || host.Address != "10.0.0.1" {
+		t.Errorf("assign next free = %d %+v; want 201 10.0.0.1", code, host)
+	}
+	var e apiError
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/30/hosts", `{"address": "10.0.0.1"}`, &e); code != http.StatusConflict {
+		t.Errorf("assign taken address = %d %+v; want 409", code, e)
+	}
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/30/hosts", `{"address": "10.0.0.3"}`, &e); code != http.StatusUnprocessableEntity {
+		t.Errorf("assign broadcast address = %d %+v; want 422", code, e)
+	}
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/30/hosts", `{"mac": "52:54:00:00:00:02", "note": "spare"}`, &host); code != http.StatusCreated || host.Address != "10.0.0.2" {
+		t.Errorf("assign next free = %d %+v; want 201 10.0.0.2", code, host)
+	}
+	if code := call(t, h, "POST", "/api/v1/subnets/10.0.0.0/30/hosts", `{}`, &e); code != http.StatusConflict {
+		t.Errorf("assign in a full subnet = %d %+v; want 409", code, e)
+	}
+
+	var s apiSubnet
+	if code := call(t, h, "DELETE", "/api/v1/subnets/10.0.0.0/30/hosts/10.0.0.1", "", &s); code != http.StatusOK || len(s.Assignments) != 1 || s.Assignments["10.0.0.2"].Note != "spare" {
+		t.Errorf("remove host = %d %+v; want 10.0.0.2 left", code, s)
+	}
+	if code := call(t, h, "DELETE", "/api/v1/subnets/10.0.0.0/30/hosts/10.0.0.1", "", &e); code != http.StatusNotFound {
+		t.Errorf("remove free address = %d %+v; want 404", code, e)
+	}
+}
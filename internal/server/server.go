@@ -0,0 +1,134 @@
+// Package server serves a subnet plan over HTTP as editable web pages.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"github.com/rochana-atapattu/subnets/internal/store"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// Server edits a plan held in a store, which saves it after every change.
+type Server struct {
+	store *store.Store
+}
+
+// New returns a server for the plan in st.
+func New(st *store.Store) *Server {
+	return &Server{store: st}
+}
+
+// Handler returns an echo instance with the server's routes registered.
+func (s *Server) Handler() *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.GET("/", s.index)
+	e.POST("/divide", s.divide)
+	e.POST("/join", s.join)
+	e.POST("/labels", s.labels)
+	s.registerAPI(e)
+	return e
+}
+
+// render writes a templ component as an HTML response.
+func render(c echo.Context, status int, t templ.Component) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(status)
+	return t.Render(c.Request().Context(), c.Response())
+}
+
+func (s *Server) index(c echo.Context) error {
+	ws, version := s.store.Snapshot()
+	return render(c, http.StatusOK, page(ws, version))
+}
+
+// update applies f to the plan through the store, turning store errors into
+// HTTP errors.
+func (s *Server) update(ifMatch uint64, f func(ws *subnet.Workspace) error) (uint64, error) {
+	version, err := s.store.Update(ifMatch, f)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		return version, echo.NewHTTPError(http.StatusPreconditionFailed, "the plan has changed since it was read; reload and try again")
+	}
+	return version, err
+}
+
+// edit looks up the node named by the "cidr" form value, applies f to it and
+// redirects back to the tree. The "version" form value, if set, must match
+// the plan's current version.
+func (s *Server) edit(c echo.Context, f func(n *subnet.Subnet) error) error {
+	address, maskLen, err := subnet.ParseCIDR(c.FormValue("cidr"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var ifMatch uint64
+	if v := c.FormValue("version"); v != "" {
+		if ifMatch, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid version")
+		}
+	}
+
+	_, err = s.update(ifMatch, func(ws *subnet.Workspace) error {
+		n := ws.Find(address, maskLen)
+		if n == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("%s is not in the plan", subnet.FormatCIDR(address, maskLen)))
+		}
+		return f(n)
+	})
+	if err != nil {
+		return err
+	}
+	return c.Redirect(http.StatusSeeOther, "/#"+subnet.FormatCIDR(address, maskLen))
+}
+
+func (s *Server) divide(c echo.Context) error {
+	return s.edit(c, divideSubnet)
+}
+
+func (s *Server) join(c echo.Context) error {
+	return s.edit(c, joinSubnet)
+}
+
+// labels replaces a node's labels and metadata with the space separated
+// fields of the "labels" form value, as in the flat plan format.
+func (s *Server) labels(c echo.Context) error {
+	labels, metadata, err := subnet.ParseFields(c.FormValue("labels"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return s.edit(c, func(n *subnet.Subnet) error {
+		if err := leafOnly(n, len(labels) > 0 || len(metadata) > 0); err != nil {
+			return err
+		}
+		n.Labels = labels
+		n.Metadata = metadata
+		return nil
+	})
+}
+
+// describe summarises a node's addresses like the TUI's description column.
+func describe(n *subnet.Subnet) string {
+	first, last := subnet.UsableRange(n.Address, n.MaskLen)
+	return fmt.Sprintf("%s | %s - %s | %d hosts",
+		subnet.InetNtoa(subnet.SubnetNetmask(n.MaskLen)),
+		subnet.InetNtoa(first), subnet.InetNtoa(last),
+		subnet.UsableHosts(n.MaskLen))
+}
+
+func isLeaf(n *subnet.Subnet) bool {
+	return n.Left == nil && n.Right == nil
+}
+
+// title lists the roots of a workspace.
+func title(ws *subnet.Workspace) string {
+	cidrs := make([]string, len(ws.Roots))
+	for i, root := range ws.Roots {
+		cidrs[i] = root.CIDR()
+	}
+	return strings.Join(cidrs, ", ")
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/store"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// newHandler returns the handler of a server for a plan of the given roots
+// saved to file.
+func newHandler(t *testing.T, file string, roots ...*subnet.Subnet) http.Handler {
+	t.Helper()
+	ws, err := subnet.NewWorkspace(roots...)
+	if err != nil {
+		t.Fatalf("NewWorkspace returned error: %v", err)
+	}
+	st, err := store.New(file, ws)
+	if err != nil {
+		t.Fatalf("store.New returned error: %v", err)
+	}
+	return New(st).Handler()
+}
+
+// post submits a form to the handler and returns the response.
+func post(h http.Handler, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEditPages(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.json")
+	root := &subnet.Subnet{Address: subnet.InetAton("10.0.0.0"), MaskLen: 24}
+	h := newHandler(t, file, root)
+
+	if rec := post(h, "/divide", url.Values{"cidr": {"10.0.0.0/24"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("POST /divide = %d; want %d", rec.Code, http.StatusSeeOther)
+	}
+	rec := post(h, "/labels", url.Values{"cidr": {"10.0.0.128/25"}, "labels": {"web prod vpc=main"}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("POST /labels = %d; want %d", rec.Code, http.StatusSeeOther)
+	}
+
+	saved, err := subnet.LoadWorkspace(file)
+	if err != nil {
+		t.Fatalf("plan was not saved: %v", err)
+	}
+	web := saved.Find(subnet.InetAton("10.0.0.128"), 25)
+	if web == nil || len(web.Labels) != 2 || web.Metadata["vpc"] != "main" {
+		t.Errorf("saved 10.0.0.128/25 = %+v; want labels web prod and vpc=main", web)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `value="web prod vpc=main"`) {
+		t.Errorf("GET / = %d, missing labels in body:\n%s", rec.Code, rec.Body.String())
+	}
+
+	// The page was rendered at version 3, after the divide and labels edits.
+	if rec := post(h, "/join", url.Values{"cidr": {"10.0.0.0/24"}, "version": {"2"}}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("POST /join with a stale version = %d; want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	if rec := post(h, "/join", url.Values{"cidr": {"10.0.0.0/24"}, "version": {"3"}}); rec.Code != http.StatusConflict {
+		t.Errorf("POST /join over a labelled half = %d; want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := post(h, "/labels", url.Values{"cidr": {"10.0.0.0/24"}, "labels": {"lab"}}); rec.Code != http.StatusConflict {
+		t.Errorf("POST /labels on a divided subnet = %d; want %d", rec.Code, http.StatusConflict)
+	}
+	post(h, "/labels", url.Values{"cidr": {"10.0.0.128/25"}, "labels": {""}})
+	post(h, "/join", url.Values{"cidr": {"10.0.0.0/24"}, "version": {"4"}})
+	if saved, _ := subnet.LoadWorkspace(file); saved.Roots[0].Left != nil {
+		t.Errorf("POST /join did not join 10.0.0.0/24")
+	}
+
+	hosted := &subnet.Subnet{Address: subnet.InetAton("10.1.0.0"), MaskLen: 24}
+	hosted.SetHost("10.1.0.127", subnet.Host{Hostname: "gw"})
+	h2 := newHandler(t, filepath.Join(t.TempDir(), "hosted.json"), hosted)
+	if rec := post(h2, "/divide", url.Values{"cidr": {"10.1.0.0/24"}}); rec.Code != http.StatusConflict {
+		t.Errorf("POST /divide putting a host on a broadcast address = %d; want %d", rec.Code, http.StatusConflict)
+	}
+
+	if rec := post(h, "/divide", url.Values{"cidr": {"10.9.0.0/24"}}); rec.Code != http.StatusNotFound {
+		t.Errorf("POST /divide outside the plan = %d; want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := post(h, "/divide", url.Values{"cidr": {"nonsense"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /divide with a bad cidr = %d; want %d", rec.Code, http.StatusBadRequest)
+	}
+}
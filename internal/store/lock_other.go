@@ -0,0 +1,35 @@
+//go:build !unix
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// lockTimeout is how long lockFile waits for another process.
+const lockTimeout = 10 * time.Second
+
+// lockFile creates a lock file next to file, waiting while another process
+// holds it. The lock file is removed on unlock.
+func lockFile(file string) (func(), error) {
+	name := file + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
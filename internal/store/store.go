@@ -0,0 +1,293 @@
+// Package store guards a subnet plan shared by concurrent editors, both
+// goroutines in one process and separate processes saving the same file.
+// A plan is a workspace of one or more root subnets.
+package store
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+var (
+	// ErrVersionMismatch is returned when an update expects a version of the
+	// plan that is no longer current.
+	ErrVersionMismatch = errors.New("plan version does not match")
+	// ErrModified is returned by Replace when another process saved the
+	// file since the store last read or wrote it.
+	ErrModified = errors.New("plan file was modified by another process")
+)
+
+// Store holds a plan and the file it is saved to. Reads share a lock, while
+// updates are serialised, applied to a copy of the plan and saved under an
+// exclusive lock on the file before they become visible. Every change,
+// including one picked up from disk, increments the version.
+type Store struct {
+	file string
+
+	mu      sync.RWMutex
+	ws      *subnet.Workspace
+	version uint64
+	// disk is the hash of the file contents last read or written, or nil if
+	// the file did not exist.
+	disk []byte
+}
+
+// Open loads the plan saved in file.
+func Open(file string) (*Store, error) {
+	s := &Store{file: file}
+	unlock, err := lockFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if s.ws, err = subnet.UnmarshalWorkspace(data, file); err != nil {
+		return nil, err
+	}
+	s.version = 1
+	s.disk = hash(data)
+	return s, nil
+}
+
+// New returns a store for a new plan that will be saved to file. Whatever
+// the file holds now is treated as read, so saving overwrites it unless
+// another process changes it first.
+func New(file string, ws *subnet.Workspace) (*Store, error) {
+	s := &Store{file: file, ws: ws.Clone(), version: 1}
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		s.disk = hash(data)
+	}
+	return s, nil
+}
+
+// File returns the name of the file the plan is saved to.
+func (s *Store) File() string {
+	return s.file
+}
+
+// Version returns the current version of the plan.
+func (s *Store) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// ETag formats a version as an HTTP entity tag.
+func ETag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// ParseETag parses an If-Match header value written by ETag. An empty value
+// or "*" matches any version and returns 0.
+func ParseETag(tag string) (uint64, error) {
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, ErrVersionMismatch
+	}
+	version, err := strconv.ParseUint(unquoted, 10, 64)
+	if err != nil {
+		return 0, ErrVersionMismatch
+	}
+	return version, nil
+}
+
+// View calls f with the current plan under a read lock and returns the
+// version f saw. f must not modify or retain the workspace.
+func (s *Store) View(f func(ws *subnet.Workspace)) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(s.ws)
+	return s.version
+}
+
+// Snapshot returns a copy of the current plan and its version.
+func (s *Store) Snapshot() (*subnet.Workspace, uint64) {
+	var ws *subnet.Workspace
+	version := s.View(func(w *subnet.Workspace) { ws = w.Clone() })
+	return ws, version
+}
+
+// Update applies f to a copy of the plan and saves the result. Changes saved
+// to the file by other processes are loaded first, so f always sees the
+// latest plan. If ifMatch is not 0 and is not the current version, Update
+// returns ErrVersionMismatch without calling f. If f returns an error the
+// plan is left unchanged.
+func (s *Store) Update(ifMatch uint64, f func(ws *subnet.Workspace) error) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.file)
+	if err != nil {
+		return s.version, err
+	}
+	defer unlock()
+
+	if err := s.refresh(); err != nil {
+		return s.version, err
+	}
+	if ifMatch != 0 && ifMatch != s.version {
+		return s.version, ErrVersionMismatch
+	}
+	ws := s.ws.Clone()
+	if err := f(ws); err != nil {
+		return s.version, err
+	}
+	if err := s.write(ws); err != nil {
+		return s.version, err
+	}
+	return s.version, nil
+}
+
+// Replace saves ws as the new plan. Unlike Update it cannot build on
+// changes made by other processes, so it returns ErrModified if the file
+// changed since the store last read or wrote it.
+func (s *Store) Replace(ifMatch uint64, ws *subnet.Workspace) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.file)
+	if err != nil {
+		return s.version, err
+	}
+	defer unlock()
+
+	if ifMatch != 0 && ifMatch != s.version {
+		return s.version, ErrVersionMismatch
+	}
+	data, err := os.ReadFile(s.file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return s.version, err
+	}
+	if !sameHash(s.disk, data, err == nil) {
+		return s.version, ErrModified
+	}
+	if err := s.write(ws.Clone()); err != nil {
+		return s.version, err
+	}
+	return s.version, nil
+}
+
+// Changed reports whether another process saved the file since the store
+// last read or wrote it.
+func (s *Store) Changed() (bool, error) {
+	data, err := os.ReadFile(s.file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !sameHash(s.disk, data, err == nil), nil
+}
+
+// Reload reads the file again, discarding any version the store holds, and
+// returns a copy of the plan and its version.
+func (s *Store) Reload() (*subnet.Workspace, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.file)
+	if err != nil {
+		return nil, s.version, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return nil, s.version, err
+	}
+	ws, err := subnet.UnmarshalWorkspace(data, s.file)
+	if err != nil {
+		return nil, s.version, err
+	}
+	s.ws = ws
+	s.disk = hash(data)
+	s.version++
+	return ws.Clone(), s.version, nil
+}
+
+// refresh loads the file if another process changed it. The caller must
+// hold s.mu and the file lock.
+func (s *Store) refresh() error {
+	data, err := os.ReadFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sameHash(s.disk, data, true) {
+		return nil
+	}
+	ws, err := subnet.UnmarshalWorkspace(data, s.file)
+	if err != nil {
+		return err
+	}
+	s.ws = ws
+	s.disk = hash(data)
+	s.version++
+	return nil
+}
+
+// write saves ws to the file through a temporary file and makes it the
+// current plan, keeping the permissions of the file it replaces. The caller
+// must hold s.mu and the file lock.
+func (s *Store) write(ws *subnet.Workspace) error {
+	data, err := subnet.MarshalWorkspace(ws, s.file)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.file), filepath.Base(s.file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(s.file); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.file); err != nil {
+		return err
+	}
+	s.ws = ws
+	s.disk = hash(data)
+	s.version++
+	return nil
+}
+
+func hash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// sameHash reports whether the file contents data, if the file exists,
+// match the hash last seen.
+func sameHash(seen, data []byte, exists bool) bool {
+	if !exists {
+		return seen == nil
+	}
+	return seen != nil && string(seen) == string(hash(data))
+}
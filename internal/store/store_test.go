@@ -0,0 +1,191 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// newPlan returns a workspace with an undivided 10.0.0.0/16 root.
+func newPlan() *subnet.Workspace {
+	return &subnet.Workspace{Roots: []*subnet.Subnet{{Address: subnet.InetAton("10.0.0.0"), MaskLen: 16}}}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.json")
+	s, err := New(file, newPlan())
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Update(0, func(ws *subnet.Workspace) error {
+				n, err := ws.Allocate(24)
+				if err == nil {
+					n.Labels = []string{fmt.Sprint(i)}
+				}
+				return err
+			})
+			if err != nil {
+				t.Errorf("Update returned error: %v", err)
+			}
+			s.View(func(ws *subnet.Workspace) { ws.Iterate(func(*subnet.Subnet) {}) })
+		}(i)
+	}
+	wg.Wait()
+
+	if v := s.Version(); v != 33 {
+		t.Errorf("Version() = %d; want 33", v)
+	}
+	saved, err := subnet.LoadWorkspace(file)
+	if err != nil {
+		t.Fatalf("plan was not saved: %v", err)
+	}
+	labelled := 0
+	saved.Iterate(func(n *subnet.Subnet) {
+		if len(n.Labels) > 0 {
+			labelled++
+		}
+	})
+	if labelled != 32 {
+		t.Errorf("saved plan has %d labelled subnets; want 32", labelled)
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	s, _ := New(filepath.Join(t.TempDir(), "plan.json"), newPlan())
+	v, err := s.Update(1, func(ws *subnet.Workspace) error {
+		ws.Roots[0].Divide()
+		return nil
+	})
+	if err != nil || v != 2 {
+		t.Fatalf("Update(1) = %d, %v; want 2", v, err)
+	}
+	called := false
+	if _, err := s.Update(1, func(*subnet.Workspace) error { called = true; return nil }); !errors.Is(err, ErrVersionMismatch) || called {
+		t.Errorf("Update with a stale version = %v, called %v; want ErrVersionMismatch", err, called)
+	}
+	if _, err := s.Replace(1, newPlan()); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Replace with a stale version = %v; want ErrVersionMismatch", err)
+	}
+
+	failed := errors.New("failed")
+	if _, err := s.Update(0, func(ws *subnet.Workspace) error { ws.Roots[0].Join(); return failed }); err != failed {
+		t.Errorf("Update = %v; want the error returned by f", err)
+	}
+	s.View(func(ws *subnet.Workspace) {
+		if ws.Roots[0].Left == nil {
+			t.Errorf("failed Update changed the plan")
+		}
+	})
+
+	if tag := ETag(2); tag != `"2"` {
+		t.Errorf("ETag(2) = %s", tag)
+	}
+	if v, err := ParseETag(`"7"`); v != 7 || err != nil {
+		t.Errorf("ParseETag = %d, %v; want 7", v, err)
+	}
+	if v, err := ParseETag("*"); v != 0 || err != nil {
+		t.Errorf("ParseETag(*) = %d, %v; want 0", v, err)
+	}
+}
+
+func TestTwoProcesses(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.plan")
+	if err := subnet.SaveWorkspace(newPlan(), file); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Open(file)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	b, _ := Open(file)
+
+	if _, err := a.Update(0, func(ws *subnet.Workspace) error {
+		root := ws.Roots[0]
+		root.Divide()
+		root.Left.Labels = []string{"a"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	// b has not seen a's save, so replacing the whole plan would lose it.
+	if _, err := b.Replace(0, newPlan()); !errors.Is(err, ErrModified) {
+		t.Errorf("Replace after another save = %v; want ErrModified", err)
+	}
+
+	// Updates build on the saved plan instead.
+	if _, err := b.Update(0, func(ws *subnet.Workspace) error {
+		root := ws.Roots[0]
+		if root.Left == nil {
+			return errors.New("update did not see the other save")
+		}
+		root.Right.Labels = []string{"b"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if _, err := a.Update(0, func(ws *subnet.Workspace) error {
+		root := ws.Roots[0]
+		if len(root.Left.Labels) == 0 || len(root.Right.Labels) == 0 {
+			return errors.New("lost a label")
+		}
+		return nil
+	}); err != nil {
+		t.Errorf("Update returned error: %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.json")
+	a, _ := New(file, newPlan())
+	a.Replace(0, newPlan())
+	b, _ := Open(file)
+	b.Update(0, func(ws *subnet.Workspace) error { ws.Roots[0].Divide(); return nil })
+
+	if changed, err := a.Changed(); !changed || err != nil {
+		t.Errorf("Changed() = %v, %v; want true after another save", changed, err)
+	}
+	if changed, err := b.Changed(); changed || err != nil {
+		t.Errorf("Changed() = %v, %v; want false after own save", changed, err)
+	}
+	if _, err := a.Replace(0, newPlan()); !errors.Is(err, ErrModified) {
+		t.Fatalf("Replace after another save = %v; want ErrModified", err)
+	}
+	ws, _, err := a.Reload()
+	if err != nil || ws.Roots[0].Left == nil {
+		t.Fatalf("Reload = %v, %v; want the divided plan", ws, err)
+	}
+	ws.Roots[0].Join()
+	if _, err := a.Replace(0, ws); err != nil {
+		t.Errorf("Replace after Reload = %v; want success", err)
+	}
+}
+
+func TestKeepsMode(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(file, []byte(`{"Roots": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := New(file, newPlan())
+	if _, err := s.Replace(0, newPlan()); err != nil {
+		t.Fatalf("Replace returned error: %v", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("saved file mode = %v; want -rw-------", info.Mode())
+	}
+}
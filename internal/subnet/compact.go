@@ -0,0 +1,45 @@
+package subnet
+
+// Compaction is a divided node that Compact joins into a single free leaf.
+type Compaction struct {
+	Subnet *Subnet
+	// Leaves is the number of free leaves the node held.
+	Leaves int
+}
+
+// Compact restores contiguous free space by joining, bottom-up, every node
+// whose children are both free leaves, including leaves made free by joins
+// below them. Nodes with labels or metadata are never joined. It returns the
+// outermost joined nodes in address order; with dryRun the tree is left as
+// it is and the joins that would be made are returned.
+func (n *Subnet) Compact(dryRun bool) []Compaction {
+	var compactions []Compaction
+	for _, block := range n.FreeBlocks() {
+		if block.Left == nil {
+			continue
+		}
+		c := Compaction{Subnet: block}
+		for range Leaves(block) {
+			c.Leaves++
+		}
+		compactions = append(compactions, c)
+	}
+	if dryRun {
+		return compactions
+	}
+	for _, c := range compactions {
+		for m := range Nodes(c.Subnet, PostOrder) {
+			m.Join()
+		}
+	}
+	return compactions
+}
+
+// Compact compacts every root of the workspace.
+func (ws *Workspace) Compact(dryRun bool) []Compaction {
+	var compactions []Compaction
+	for _, root := range ws.Roots {
+		compactions = append(compactions, root.Compact(dryRun)...)
+	}
+	return compactions
+}
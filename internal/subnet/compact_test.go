@@ -0,0 +1,62 @@
+package subnet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	plan := `10.0.0.0/26 web
+10.0.0.64/27
+10.0.0.96/27
+10.0.0.128/27
+10.0.0.160/28
+10.0.0.176/28
+10.0.0.192/26 db
+10.0.1.0/25
+10.0.1.128/26
+10.0.1.192/27
+10.0.1.224/28 owner=ops
+10.0.1.240/28
+`
+	tests := []struct {
+		dryRun bool
+		want   string
+	}{
+		{true, plan},
+		{false, `10.0.0.0/26 web
+10.0.0.64/26
+10.0.0.128/26
+10.0.0.192/26 db
+10.0.1.0/25
+10.0.1.128/26
+10.0.1.192/27
+10.0.1.224/28 owner=ops
+10.0.1.240/28
+`},
+	}
+	for _, test := range tests {
+		root, err := ReadFlat(strings.NewReader(plan))
+		if err != nil {
+			t.Fatalf("ReadFlat returned error: %v", err)
+		}
+		var got []string
+		for _, c := range root.Compact(test.dryRun) {
+			got = append(got, c.Subnet.CIDR())
+			if c.Subnet.CIDR() == "10.0.0.128/26" && c.Leaves != 3 {
+				t.Errorf("Compact(%t): 10.0.0.128/26 held %d leaves, want 3", test.dryRun, c.Leaves)
+			}
+		}
+		if want := "10.0.0.64/26 10.0.0.128/26"; strings.Join(got, " ") != want {
+			t.Errorf("Compact(%t) = %s, want %s", test.dryRun, strings.Join(got, " "), want)
+		}
+		var buf bytes.Buffer
+		if err := WriteFlat(&buf, root); err != nil {
+			t.Fatalf("WriteFlat returned error: %v", err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("Compact(%t) left\n%s\nwant\n%s", test.dryRun, buf.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package subnet
+
+// ChangeKind is the kind of difference between two plans at one subnet.
+type ChangeKind string
+
+const (
+	// Added is a subnet that appears in the new plan only.
+	Added ChangeKind = "added"
+	// Removed is a subnet that appears in the old plan only.
+	Removed ChangeKind = "removed"
+	// Split is a subnet divided in the new plan but not in the old one.
+	Split ChangeKind = "split"
+	// Joined is a subnet divided in the old plan but not in the new one.
+	Joined ChangeKind = "joined"
+	// Relabelled is a subnet whose labels, metadata or hosts changed.
+	Relabelled ChangeKind = "relabelled"
+)
+
+// Change is one difference reported by Diff. Old and new labels and metadata
+// are set for the side or sides the subnet exists on, and hosts for
+// relabelled subnets.
+type Change struct {
+	Kind        ChangeKind        `json:"kind"`
+	CIDR        string            `json:"cidr"`
+	OldLabels   []string          `json:"old_labels,omitempty"`
+	OldMetadata map[string]string `json:"old_metadata,omitempty"`
+	NewLabels   []string          `json:"new_labels,omitempty"`
+	NewMetadata map[string]string `json:"new_metadata,omitempty"`
+	OldHosts    map[string]Host   `json:"old_hosts,omitempty"`
+	NewHosts    map[string]Host   `json:"new_hosts,omitempty"`
+}
+
+// Diff returns the changes that turn the old tree into the new one, in
+// address order. A split subnet is followed by the subnets added under it and
+// a joined subnet by the subnets removed under it. Added and removed subnets
+// are the leaves and the labelled intermediate subnets.
+func Diff(old, new *Subnet) []Change {
+	var changes []Change
+	if old.Address != new.Address || old.MaskLen != new.MaskLen {
+		changes = append(changes, Change{Kind: Removed, CIDR: old.CIDR(), OldLabels: old.Labels, OldMetadata: old.Metadata})
+		added(new, &changes)
+		return changes
+	}
+	diff(old, new, &changes)
+	return changes
+}
+
+// DiffWorkspaces returns the changes between two workspaces, in address
+// order. A root in only one of them is reported as removed or added, followed
+// by its subnets.
+func DiffWorkspaces(old, new *Workspace) []Change {
+	var changes []Change
+	i, j := 0, 0
+	for i < len(old.Roots) || j < len(new.Roots) {
+		switch {
+		case j == len(new.Roots) || i < len(old.Roots) && old.Roots[i].Address < new.Roots[j].Address:
+			root := old.Roots[i]
+			changes = append(changes, Change{Kind: Removed, CIDR: root.CIDR(), OldLabels: root.Labels, OldMetadata: root.Metadata})
+			removed(root.Left, &changes)
+			removed(root.Right, &changes)
+			i++
+		case i == len(old.Roots) || new.Roots[j].Address < old.Roots[i].Address:
+			root := new.Roots[j]
+			changes = append(changes, Change{Kind: Added, CIDR: root.CIDR(), NewLabels: root.Labels, NewMetadata: root.Metadata})
+			added(root.Left, &changes)
+			added(root.Right, &changes)
+			j++
+		default:
+			changes = append(changes, Diff(old.Roots[i], new.Roots[j])...)
+			i++
+			j++
+		}
+	}
+	return changes
+}
+
+func diff(old, new *Subnet, changes *[]Change) {
+	if !sameAttributes(old, new) {
+		*changes = append(*changes, Change{
+			Kind:        Relabelled,
+			CIDR:        old.CIDR(),
+			OldLabels:   old.Labels,
+			OldMetadata: old.Metadata,
+			NewLabels:   new.Labels,
+			NewMetadata: new.Metadata,
+			OldHosts:    old.Hosts,
+			NewHosts:    new.Hosts,
+		})
+	}
+	oldLeaf := old.Left == nil && old.Right == nil
+	newLeaf := new.Left == nil && new.Right == nil
+	switch {
+	case oldLeaf && newLeaf:
+	case oldLeaf:
+		*changes = append(*changes, Change{Kind: Split, CIDR: old.CIDR()})
+		added(new.Left, changes)
+		added(new.Right, changes)
+	case newLeaf:
+		*changes = append(*changes, Change{Kind: Joined, CIDR: old.CIDR()})
+		removed(old.Left, changes)
+		removed(old.Right, changes)
+	default:
+		diff(old.Left, new.Left, changes)
+		diff(old.Right, new.Right, changes)
+	}
+}
+
+// reported reports whether n is listed when its subtree is added or removed.
+func reported(n *Subnet) bool {
+	return n.Left == nil && n.Right == nil || len(n.Labels) > 0 || len(n.Metadata) > 0
+}
+
+func added(n *Subnet, changes *[]Change) {
+	walkSubtree(n, func(n *Subnet) {
+		*changes = append(*changes, Change{Kind: Added, CIDR: n.CIDR(), NewLabels: n.Labels, NewMetadata: n.Metadata})
+	})
+}
+
+func removed(n *Subnet, changes *[]Change) {
+	walkSubtree(n, func(n *Subnet) {
+		*changes = append(*changes, Change{Kind: Removed, CIDR: n.CIDR(), OldLabels: n.Labels, OldMetadata: n.Metadata})
+	})
+}
+
+// walkSubtree calls f for the reported nodes of n's subtree in address order.
+func walkSubtree(n *Subnet, f func(n *Subnet)) {
+	if n == nil {
+		return
+	}
+	if reported(n) {
+		f(n)
+	}
+	walkSubtree(n.Left, f)
+	walkSubtree(n.Right, f)
+}
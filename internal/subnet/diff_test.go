@@ -0,0 +1,91 @@
+package subnet
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := flatPlan(t, `10.0.0.0/17 web
+10.0.128.0/18 db
+10.0.192.0/18
+`)
+	testCases := []struct {
+		name string
+		new  string
+		want []string
+	}{
+		{
+			name: "unchanged",
+			new:  "10.0.0.0/17 web\n10.0.128.0/18 db\n10.0.192.0/18\n",
+		},
+		{
+			name: "split",
+			new:  "10.0.0.0/18 web\n10.0.64.0/18 api\n10.0.128.0/18 db\n10.0.192.0/18\n",
+			want: []string{
+				"relabelled 10.0.0.0/17 [web] -> []",
+				"split 10.0.0.0/17 [] -> []",
+				"added 10.0.0.0/18 [] -> [web]",
+				"added 10.0.64.0/18 [] -> [api]",
+			},
+		},
+		{
+			name: "joined",
+			new:  "10.0.0.0/17 web\n10.0.128.0/17\n",
+			want: []string{
+				"joined 10.0.128.0/17 [] -> []",
+				"removed 10.0.128.0/18 [db] -> []",
+				"removed 10.0.192.0/18 [] -> []",
+			},
+		},
+		{
+			name: "relabelled",
+			new:  "10.0.0.0/17 web\n10.0.128.0/18 db\n10.0.192.0/18 cache az=a\n",
+			want: []string{"relabelled 10.0.192.0/18 [] -> [cache]"},
+		},
+		{
+			name: "host added",
+			new:  "10.0.0.0/17 web\n  host 10.0.0.5 hostname=web1\n10.0.128.0/18 db\n10.0.192.0/18\n",
+			want: []string{"relabelled 10.0.0.0/17 [web] -> [web]"},
+		},
+		{
+			name: "different root",
+			new:  "10.1.0.0/16 other\n",
+			want: []string{
+				"removed 10.0.0.0/16 [] -> []",
+				"added 10.1.0.0/16 [] -> [other]",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, c := range Diff(old, flatPlan(t, tc.new)) {
+				got = append(got, fmt.Sprintf("%s %s %v -> %v", c.Kind, c.CIDR, c.OldLabels, c.NewLabels))
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("Diff() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiffWorkspaces(t *testing.T) {
+	old, _ := NewWorkspace(flatPlan(t, "10.0.0.0/9 web\n10.128.0.0/9\n"), flatPlan(t, "172.16.0.0/12\n"))
+	new, _ := NewWorkspace(flatPlan(t, "10.0.0.0/9 web\n10.128.0.0/9 db\n"), flatPlan(t, "192.168.0.0/25 lab\n192.168.0.128/25\n"))
+	var got []string
+	for _, c := range DiffWorkspaces(old, new) {
+		got = append(got, string(c.Kind)+" "+c.CIDR)
+	}
+	want := []string{
+		"relabelled 10.128.0.0/9",
+		"removed 172.16.0.0/12",
+		"added 192.168.0.0/24",
+		"added 192.168.0.0/25",
+		"added 192.168.0.128/25",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffWorkspaces() = %q, want %q", got, want)
+	}
+}
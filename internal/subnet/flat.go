@@ -0,0 +1,364 @@
+package subnet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WriteFlat writes the tree in the flat plan format: one line per leaf,
+// sorted by address, holding the CIDR followed by its labels and then its
+// metadata as key=value pairs and the number of addresses in use as
+// used=<n>. A leaf's hosts follow it on "host" lines,
+// with an optional note after a "#". Divided subnets with labels, metadata
+// or hosts get a line of their own before the subnets inside them.
+//
+//	10.0.0.0/23 vpc=main
+//	10.0.0.0/24 web prod
+//	  host 10.0.0.5 hostname=web1 mac=52:54:00:12:34:56 # rack 4
+//	10.0.1.0/24
+func WriteFlat(w io.Writer, root *Subnet) error {
+	bw := bufio.NewWriter(w)
+	for n := range Nodes(root, PreOrder) {
+		if (n.Left != nil || n.Right != nil) && !n.annotated() {
+			continue
+		}
+		if err := CheckMetadata(n.Metadata); err != nil {
+			return fmt.Errorf("%s: %w", n.CIDR(), err)
+		}
+		fields := append([]string{n.CIDR()}, n.Fields()...)
+		if n.InUse != nil {
+			fields = append(fields, UsedKey+"="+strconv.FormatUint(*n.InUse, 10))
+		}
+		fmt.Fprintln(bw, strings.Join(fields, " "))
+		for _, address := range n.HostAddresses() {
+			fields := append([]string{"  host", address}, n.Hosts[address].fields()...)
+			fmt.Fprintln(bw, strings.Join(fields, " "))
+		}
+	}
+	return bw.Flush()
+}
+
+// annotated reports whether n has labels, metadata, hosts or usage.
+func (n *Subnet) annotated() bool {
+	return len(n.Labels) > 0 || len(n.Metadata) > 0 || len(n.Hosts) > 0 || n.InUse != nil
+}
+
+// Fields returns the labels of n followed by its metadata as key=value
+// pairs sorted by key, as written in the flat plan format. Labels, keys and
+// values that are empty or hold spaces, quotes, "=" or "#" are double quoted.
+func (n *Subnet) Fields() []string {
+	fields := make([]string, 0, len(n.Labels)+len(n.Metadata))
+	for _, label := range n.Labels {
+		fields = append(fields, quoteField(label))
+	}
+	for _, k := range SortedKeys(n.Metadata) {
+		fields = append(fields, quoteField(k)+"="+quoteField(n.Metadata[k]))
+	}
+	return fields
+}
+
+// quoteField double quotes s, with Go escapes, if reading it back as a bare
+// field would split or change it.
+func quoteField(s string) string {
+	special := func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsPrint(r) || strings.ContainsRune(`"=#`, r)
+	}
+	if s == "" || strings.IndexFunc(s, special) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// ParseFields parses space separated fields written by Fields back into
+// labels and metadata.
+func ParseFields(s string) ([]string, map[string]string, error) {
+	fields, comment, err := splitFields(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if comment != "" {
+		return nil, nil, fmt.Errorf("invalid fields %q: quote \"#\"", s)
+	}
+	labels, metadata, err := parseFields(fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return labels, metadata, CheckMetadata(metadata)
+}
+
+// FormatLabels joins labels with spaces, quoting them as Fields does.
+func FormatLabels(labels []string) string {
+	fields := make([]string, len(labels))
+	for i, label := range labels {
+		fields[i] = quoteField(label)
+	}
+	return strings.Join(fields, " ")
+}
+
+// ParseLabels parses labels written by FormatLabels. Unquoted "=" and "#"
+// are refused, as they would start metadata or a comment in a flat plan.
+func ParseLabels(s string) ([]string, error) {
+	fields, comment, err := splitFields(s)
+	if err != nil {
+		return nil, err
+	}
+	labels, metadata, err := parseFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	if comment != "" || len(metadata) > 0 {
+		return nil, fmt.Errorf("invalid labels %q: quote \"=\" and \"#\"", s)
+	}
+	return labels, nil
+}
+
+// CheckMetadata reports whether metadata can be saved in a plan: keys must
+// not be empty, and UsedKey is reserved for the number of addresses in use.
+func CheckMetadata(metadata map[string]string) error {
+	for _, k := range SortedKeys(metadata) {
+		switch k {
+		case "":
+			return fmt.Errorf("empty metadata key")
+		case UsedKey:
+			return fmt.Errorf("metadata key %q is reserved for usage", k)
+		}
+	}
+	return nil
+}
+
+// splitFields splits a line of a flat plan at spaces outside double quotes.
+// A quote may open a field or follow the "=" of a key=value pair. A field
+// starting with "#" begins a comment, which is returned with the "#".
+func splitFields(line string) ([]string, string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case r == '#':
+			return fields, line[i:], nil
+		}
+		start := i
+		for i < len(line) {
+			r, size := utf8.DecodeRuneInString(line[i:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			if r == '"' && (i == start || line[i-1] == '=') {
+				quoted, err := strconv.QuotedPrefix(line[i:])
+				if err != nil {
+					return nil, "", fmt.Errorf("unterminated quote in %q", line[start:])
+				}
+				size = len(quoted)
+			}
+			i += size
+		}
+		fields = append(fields, line[start:i])
+	}
+	return fields, "", nil
+}
+
+// parseFields splits fields returned by splitFields into labels and
+// key=value metadata, removing quotes.
+func parseFields(fields []string) ([]string, map[string]string, error) {
+	var labels []string
+	var metadata map[string]string
+	for _, field := range fields {
+		k, rest, err := unquoteField(field, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rest == "" {
+			labels = append(labels, k)
+			continue
+		}
+		v, rest, err := unquoteField(rest[1:], false)
+		if err != nil || k == "" || rest != "" {
+			return nil, nil, fmt.Errorf("invalid field %q", field)
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[k] = v
+	}
+	return labels, metadata, nil
+}
+
+// unquoteField returns the text at the start of s, unquoted if it is double
+// quoted, and what follows it. Bare text ends at the first "=" if key is
+// set, and runs to the end of s otherwise.
+func unquoteField(s string, key bool) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid quoted field %q", s)
+		}
+		text, _ := strconv.Unquote(quoted)
+		return text, s[len(quoted):], nil
+	}
+	if i := strings.IndexByte(s, '='); key && i >= 0 {
+		return s[:i], s[i:], nil
+	}
+	return s, "", nil
+}
+
+// ReadFlat reads a plan in the flat format and rebuilds the tree by dividing
+// the smallest prefix covering every line down to each listed leaf. Fields
+// containing "=" outside double quotes are metadata, the rest are labels.
+// Blank lines and text from a field starting with "#" are ignored.
+func ReadFlat(r io.Reader) (*Subnet, error) {
+	ws, err := ReadFlatWorkspace(r)
+	if err != nil {
+		return nil, err
+	}
+	return ws.Single()
+}
+
+// flatLeaf is a subnet line of a flat plan.
+type flatLeaf struct {
+	line     int
+	address  uint32
+	maskLen  uint32
+	labels   []string
+	metadata map[string]string
+	inUse    *uint64
+	hosts    []flatHost
+}
+
+// flatHost is a "host" line of a flat plan.
+type flatHost struct {
+	line    int
+	address string
+	host    Host
+}
+
+// flatRoot is a "root" line of a flat plan and the subnet lines after it.
+// root is nil in plans without root lines, where it is inferred.
+type flatRoot struct {
+	line   int
+	root   *Subnet
+	leaves []flatLeaf
+}
+
+// parseFlat splits a flat plan into its roots and their subnet lines.
+func parseFlat(r io.Reader) ([]flatRoot, error) {
+	var roots []flatRoot
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields, comment, err := splitFields(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "root" {
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: want \"root <cidr>\"", line)
+			}
+			address, maskLen, err := ParseCIDR(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if len(roots) == 1 && roots[0].root == nil {
+				return nil, fmt.Errorf("line %d: subnets listed before the first root", roots[0].leaves[0].line)
+			}
+			roots = append(roots, flatRoot{line: line, root: &Subnet{Address: address, MaskLen: maskLen}})
+			continue
+		}
+		if fields[0] == "host" {
+			if len(roots) == 0 || len(roots[len(roots)-1].leaves) == 0 {
+				return nil, fmt.Errorf("line %d: host listed before its subnet", line)
+			}
+			address, host, err := parseHost(fields[1:], comment)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			leaves := roots[len(roots)-1].leaves
+			leaf := &leaves[len(leaves)-1]
+			leaf.hosts = append(leaf.hosts, flatHost{line, address, host})
+			continue
+		}
+		address, maskLen, err := ParseCIDR(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(roots) == 0 {
+			roots = append(roots, flatRoot{})
+		}
+		labels, metadata, err := parseFields(fields[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		inUse, err := parseUsed(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(metadata) == 0 {
+			metadata = nil
+		}
+		last := &roots[len(roots)-1]
+		last.leaves = append(last.leaves, flatLeaf{line, address, maskLen, labels, metadata, inUse, nil})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// build divides the root down to each subnet line. Without a root line the
+// root is the smallest prefix covering every line. A line may only be
+// followed by subnets inside it if it has labels, metadata or hosts, as
+// WriteFlat writes for divided subnets.
+func (f flatRoot) build() (*Subnet, error) {
+	root := f.root
+	if root == nil {
+		first, last := f.leaves[0].address, SubnetLastAddress(f.leaves[0].address, f.leaves[0].maskLen)
+		for _, l := range f.leaves[1:] {
+			first = min(first, l.address)
+			last = max(last, SubnetLastAddress(l.address, l.maskLen))
+		}
+		root = &Subnet{Address: first, MaskLen: CommonMaskLen(first, last)}
+		root.Address = NetworkAddress(first, root.MaskLen)
+	}
+
+	listed := make(map[*Subnet]int)
+	for _, l := range f.leaves {
+		node, err := root.DivideTo(l.address, l.maskLen)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", l.line, err)
+		}
+		if node.Left != nil || node.Right != nil {
+			return nil, fmt.Errorf("line %d: %s overlaps a more specific subnet", l.line, node.CIDR())
+		}
+		for p := node; p != nil; p = p.Parent {
+			if other, ok := listed[p]; ok && (p == node || !p.annotated()) {
+				return nil, fmt.Errorf("line %d: %s overlaps %s on line %d", l.line, node.CIDR(), p.CIDR(), other)
+			}
+		}
+		listed[node] = l.line
+		node.Labels = l.labels
+		node.Metadata = l.metadata
+		node.InUse = l.inUse
+		for _, h := range l.hosts {
+			if err := node.SetHost(h.address, h.host); err != nil {
+				return nil, fmt.Errorf("line %d: %w", h.line, err)
+			}
+		}
+	}
+	return root, nil
+}
+
+// isFlat reports whether filename should use the flat plan format rather than JSON.
+func isFlat(filename string) bool {
+	ext := filepath.Ext(filename)
+	return ext == ".plan" || ext == ".txt"
+}
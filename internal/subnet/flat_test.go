@@ -0,0 +1,180 @@
+package subnet
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFlatRoundTrip(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 22}
+	web, _ := root.DivideTo(InetAton("10.0.0.0"), 24)
+	web.Labels = []string{"web", "prod"}
+	db, _ := root.DivideTo(InetAton("10.0.1.0"), 25)
+	db.Labels = []string{"db"}
+	db.Metadata = map[string]string{"vpc": "main", "az": "a"}
+
+	var buf bytes.Buffer
+	if err := WriteFlat(&buf, root); err != nil {
+		t.Fatalf("WriteFlat returned error: %v", err)
+	}
+	want := `10.0.0.0/24 web prod
+10.0.1.0/25 db az=a vpc=main
+10.0.1.128/25
+10.0.2.0/23
+`
+	if buf.String() != want {
+		t.Errorf("WriteFlat wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	loaded, err := ReadFlat(strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("ReadFlat returned error: %v", err)
+	}
+	if loaded.CIDR() != "10.0.0.0/22" {
+		t.Errorf("ReadFlat root = %s; want 10.0.0.0/22", loaded.CIDR())
+	}
+	var again bytes.Buffer
+	WriteFlat(&again, loaded)
+	if again.String() != want {
+		t.Errorf("round trip wrote\n%s\nwant\n%s", again.String(), want)
+	}
+	if n := loaded.Find(InetAton("10.0.1.0"), 25); n == nil || n.Parent == nil || n.Parent.CIDR() != "10.0.1.0/24" {
+		t.Errorf("ReadFlat did not rebuild parent links for 10.0.1.0/25")
+	} else if n.Metadata["vpc"] != "main" || len(n.Labels) != 1 {
+		t.Errorf("ReadFlat 10.0.1.0/25 labels %v metadata %v; want [db] with vpc=main", n.Labels, n.Metadata)
+	}
+}
+
+func TestFlatDivided(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 22, Metadata: map[string]string{"vpc": "main"}}
+	web, _ := root.DivideTo(InetAton("10.0.0.0"), 24)
+	web.Labels = []string{"web"}
+	root.DivideTo(InetAton("10.0.0.0"), 25)
+
+	var buf bytes.Buffer
+	if err := WriteFlat(&buf, root); err != nil {
+		t.Fatalf("WriteFlat returned error: %v", err)
+	}
+	want := `10.0.0.0/22 vpc=main
+10.0.0.0/24 web
+10.0.0.0/25
+10.0.0.128/25
+10.0.1.0/24
+10.0.2.0/23
+`
+	if buf.String() != want {
+		t.Errorf("WriteFlat wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+	loaded, err := ReadFlat(strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("ReadFlat returned error: %v", err)
+	}
+	if !Equal(loaded, root) {
+		var got bytes.Buffer
+		WriteFlat(&got, loaded)
+		t.Errorf("ReadFlat rebuilt\n%s\nwant\n%s", got.String(), want)
+	}
+}
+
+func TestFlatQuoting(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 23}
+	root.Divide()
+	root.Left.Labels = []string{"a=b", "two words", "#1", ""}
+	root.Left.Metadata = map[string]string{"note": "rack 4", "odd key": `say "hi"`, "empty": "", "url": "a=b"}
+	root.Left.SetHost("10.0.0.5", Host{Hostname: "web 1", Note: "core # switch"})
+
+	var buf bytes.Buffer
+	if err := WriteFlat(&buf, root); err != nil {
+		t.Fatalf("WriteFlat returned error: %v", err)
+	}
+	want := `10.0.0.0/24 "a=b" "two words" "#1" "" empty="" note="rack 4" "odd key"="say \"hi\"" url="a=b"
+  host 10.0.0.5 hostname="web 1" # core # switch
+10.0.1.0/24
+`
+	if buf.String() != want {
+		t.Errorf("WriteFlat wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+	loaded, err := ReadFlat(strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("ReadFlat returned error: %v", err)
+	}
+	if !Equal(loaded, root) {
+		t.Errorf("ReadFlat = %+v %+v; want %+v %+v", loaded.Left.Labels, loaded.Left.Metadata, root.Left.Labels, root.Left.Metadata)
+	}
+
+	// Unquoted values written by earlier versions keep their meaning.
+	labels, metadata, err := ParseFields(`web url=a=b say"hi"`)
+	if err != nil || len(labels) != 2 || labels[1] != `say"hi"` || metadata["url"] != "a=b" {
+		t.Errorf("ParseFields = %q, %v, %v", labels, metadata, err)
+	}
+	for _, bad := range []string{`"web`, `note="rack 4`, `=x`, `web #1`, `a="b"c`} {
+		if _, _, err := ParseFields(bad); err == nil {
+			t.Errorf("ParseFields(%q) succeeded; want error", bad)
+		}
+	}
+
+	formatted := FormatLabels(root.Left.Labels)
+	if formatted != `"a=b" "two words" "#1" ""` {
+		t.Errorf("FormatLabels = %s", formatted)
+	}
+	if labels, err := ParseLabels(formatted); err != nil || !slices.Equal(labels, root.Left.Labels) {
+		t.Errorf("ParseLabels(%s) = %q, %v", formatted, labels, err)
+	}
+	for _, bad := range []string{"web env=prod", "web #1", `"web`} {
+		if _, err := ParseLabels(bad); err == nil {
+			t.Errorf("ParseLabels(%q) succeeded; want error", bad)
+		}
+	}
+}
+
+func TestReadFlatErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", "# nothing here\n"},
+		{"bad cidr", "10.0.0.0/24\n10.0.1.0\n"},
+		{"host bits", "10.0.0.1/24\n10.0.1.0/24\n"},
+		{"contains earlier", "10.0.0.0/25\n10.0.0.0/24\n10.0.1.0/24\n"},
+		{"inside earlier", "10.0.0.0/24\n10.0.0.128/25\n10.0.1.0/24\n"},
+		{"listed twice", "10.0.0.0/24 web\n10.0.0.0/24 db\n10.0.1.0/24\n"},
+		{"host first", "host 10.0.0.5\n10.0.0.0/24\n"},
+		{"host outside", "10.0.0.0/24\n  host 10.0.1.5\n10.0.1.0/24\n"},
+		{"host broadcast", "10.0.0.0/24\n  host 10.0.0.255\n10.0.1.0/24\n"},
+		{"host field", "10.0.0.0/24\n  host 10.0.0.5 owner=me\n"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := ReadFlat(strings.NewReader(testCase.input)); err == nil {
+				t.Errorf("ReadFlat(%q) succeeded; want error", testCase.input)
+			}
+		})
+	}
+}
+
+func TestFlatHosts(t *testing.T) {
+	plan := `10.0.0.0/25 mgmt
+  host 10.0.0.2 mac=52:54:00:00:00:02
+  host 10.0.0.10 hostname=switch1 # core switch, rack 4
+10.0.0.128/25
+`
+	root, err := ReadFlat(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("ReadFlat returned error: %v", err)
+	}
+	mgmt := root.Find(InetAton("10.0.0.0"), 25)
+	if h := mgmt.Hosts["10.0.0.10"]; h.Hostname != "switch1" || h.Note != "core switch, rack 4" || len(mgmt.Hosts) != 2 {
+		t.Errorf("ReadFlat hosts = %v; want 2 hosts with 10.0.0.10 switch1 and its note", mgmt.Hosts)
+	}
+	var buf bytes.Buffer
+	WriteFlat(&buf, root)
+	if buf.String() != plan {
+		t.Errorf("round trip wrote\n%s\nwant\n%s", buf.String(), plan)
+	}
+	if clone := root.Clone(); !Equal(clone, root) {
+		t.Errorf("Clone lost hosts")
+	}
+}
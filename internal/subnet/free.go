@@ -0,0 +1,29 @@
+package subnet
+
+// FreeBlocks returns the unallocated space under n in address order. Free
+// leaves are merged into the largest aligned blocks they fill: a node is a
+// free block when it has no labels or metadata and is a free leaf or has two
+// free halves. The returned blocks are nodes of the tree.
+func (n *Subnet) FreeBlocks() []*Subnet {
+	free := make(map[*Subnet]bool)
+	markFree(n, free)
+	var blocks []*Subnet
+	n.Walk(func(m *Subnet) {
+		if free[m] && (m == n || !free[m.Parent]) {
+			blocks = append(blocks, m)
+		}
+	})
+	return blocks
+}
+
+// markFree records in free whether each node under n is a free block and
+// reports whether n is.
+func markFree(n *Subnet, free map[*Subnet]bool) bool {
+	if n.Left == nil || n.Right == nil {
+		free[n] = n.IsFree()
+		return free[n]
+	}
+	left, right := markFree(n.Left, free), markFree(n.Right, free)
+	free[n] = left && right && len(n.Labels) == 0 && len(n.Metadata) == 0
+	return free[n]
+}
@@ -0,0 +1,48 @@
+package subnet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFreeBlocks(t *testing.T) {
+	testCases := []struct {
+		name string
+		plan string
+		want string
+	}{
+		{"undivided", "10.0.0.0/24", "10.0.0.0/24"},
+		{"free halves merge", "10.0.0.0/26\n10.0.0.64/26\n10.0.0.128/25 web", "10.0.0.0/25"},
+		{"not aligned", "10.0.0.0/26 web\n10.0.0.64/26\n10.0.0.128/26\n10.0.0.192/26 db", "10.0.0.64/26 10.0.0.128/26"},
+		{"full", "10.0.0.0/25 web\n10.0.0.128/25 db", ""},
+		{"metadata", "10.0.0.0/25 vpc=main\n10.0.0.128/26\n10.0.0.192/26", "10.0.0.128/25"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root, err := ReadFlat(strings.NewReader(tc.plan))
+			if err != nil {
+				t.Fatalf("ReadFlat returned error: %v", err)
+			}
+			var got []string
+			for _, n := range root.FreeBlocks() {
+				got = append(got, n.CIDR())
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Errorf("FreeBlocks() = %v; want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFreeBlocksLabelledParent(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	root.Divide()
+	root.Labels = []string{"prod"}
+	var got []string
+	for _, n := range root.FreeBlocks() {
+		got = append(got, n.CIDR())
+	}
+	if want := "10.0.0.0/25 10.0.0.128/25"; strings.Join(got, " ") != want {
+		t.Errorf("FreeBlocks() = %v; want %s", got, want)
+	}
+}
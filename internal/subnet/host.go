@@ -0,0 +1,188 @@
+package subnet
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Host is an address assigned in a leaf subnet and the machine using it.
+type Host struct {
+	Hostname string `json:"hostname,omitempty"`
+	MAC      string `json:"mac,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// SetHost assigns address to a host. The address must be in the usable range
+// of the leaf n, so not its network or broadcast address.
+func (n *Subnet) SetHost(address string, h Host) error {
+	ip, err := ParseIP(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	if n.Left != nil || n.Right != nil {
+		return fmt.Errorf("%s is divided; add hosts to its leaves", n.CIDR())
+	}
+	first, last := UsableRange(n.Address, n.MaskLen)
+	if ip < first || ip > last {
+		return fmt.Errorf("%s is not a usable address of %s", address, n.CIDR())
+	}
+	if n.Hosts == nil {
+		n.Hosts = make(map[string]Host)
+	}
+	n.Hosts[address] = h
+	return nil
+}
+
+// CanDivide reports why n cannot be divided: it is already divided, it is a
+// single address, it records addresses in use, which could not be split
+// between the halves, or one of its hosts would be the network or broadcast
+// address of the half it moves to.
+func (n *Subnet) CanDivide() error {
+	if n.Left != nil || n.Right != nil {
+		return fmt.Errorf("%s is already divided", n.CIDR())
+	}
+	if n.MaskLen >= 32 {
+		return fmt.Errorf("%s cannot be divided", n.CIDR())
+	}
+	if n.InUse != nil && *n.InUse > 0 {
+		return fmt.Errorf("%s has %d addresses in use; set its usage to 0 first", n.CIDR(), *n.InUse)
+	}
+	for _, address := range n.HostAddresses() {
+		ip := InetAton(address)
+		half := NetworkAddress(ip, n.MaskLen+1)
+		if first, last := UsableRange(half, n.MaskLen+1); ip < first || ip > last {
+			return fmt.Errorf("host %s would not be usable in %s; remove it first", address, FormatCIDR(half, n.MaskLen+1))
+		}
+	}
+	return nil
+}
+
+// splitHosts moves the hosts of n, just divided, to the half containing each.
+func (n *Subnet) splitHosts() {
+	for address, h := range n.Hosts {
+		half := n.Left
+		if n.Right.Contains(InetAton(address), 32) {
+			half = n.Right
+		}
+		if half.Hosts == nil {
+			half.Hosts = make(map[string]Host)
+		}
+		half.Hosts[address] = h
+	}
+	n.Hosts = nil
+}
+
+// CanJoin reports why n cannot be joined: it is not divided, or a half is
+// divided or has labels, metadata or usage that joining would drop. The
+// hosts of the halves move to n.
+func (n *Subnet) CanJoin() error {
+	if n.Left == nil || n.Right == nil {
+		return fmt.Errorf("%s is not divided", n.CIDR())
+	}
+	for _, half := range []*Subnet{n.Left, n.Right} {
+		if half.Left != nil || half.Right != nil || len(half.Labels) > 0 || len(half.Metadata) > 0 || half.InUse != nil && *half.InUse > 0 {
+			return fmt.Errorf("%s holds allocated subnets; free its halves first", n.CIDR())
+		}
+	}
+	return nil
+}
+
+// joinHosts moves the hosts of every subnet inside n, about to be joined,
+// to n.
+func (n *Subnet) joinHosts() {
+	for _, half := range []*Subnet{n.Left, n.Right} {
+		for m := range Nodes(half, PreOrder) {
+			for address, h := range m.Hosts {
+				if n.Hosts == nil {
+					n.Hosts = make(map[string]Host)
+				}
+				n.Hosts[address] = h
+			}
+		}
+	}
+}
+
+// RemoveHost removes the assignment of address.
+func (n *Subnet) RemoveHost(address string) error {
+	if _, ok := n.Hosts[address]; !ok {
+		return fmt.Errorf("%s is not assigned in %s", address, n.CIDR())
+	}
+	delete(n.Hosts, address)
+	if len(n.Hosts) == 0 {
+		n.Hosts = nil
+	}
+	return nil
+}
+
+// NextFreeHost returns the lowest usable address of the leaf n that is not
+// assigned to a host.
+func (n *Subnet) NextFreeHost() (string, error) {
+	if n.Left != nil || n.Right != nil {
+		return "", fmt.Errorf("%s is divided; assign hosts in its leaves", n.CIDR())
+	}
+	first, last := UsableRange(n.Address, n.MaskLen)
+	for ip := uint64(first); ip <= uint64(last); ip++ {
+		address := InetNtoa(uint32(ip))
+		if _, ok := n.Hosts[address]; !ok {
+			return address, nil
+		}
+	}
+	return "", fmt.Errorf("no free address left in %s", n.CIDR())
+}
+
+// HostAddresses returns the addresses of n's hosts in numeric order.
+func (n *Subnet) HostAddresses() []string {
+	addresses := make([]string, 0, len(n.Hosts))
+	for address := range n.Hosts {
+		addresses = append(addresses, address)
+	}
+	slices.SortFunc(addresses, func(a, b string) int {
+		return cmp.Compare(InetAton(a), InetAton(b))
+	})
+	return addresses
+}
+
+// fields returns the host as key=value pairs for the flat plan format,
+// followed by its note after a "#".
+func (h Host) fields() []string {
+	var fields []string
+	if h.Hostname != "" {
+		fields = append(fields, "hostname="+quoteField(h.Hostname))
+	}
+	if h.MAC != "" {
+		fields = append(fields, "mac="+quoteField(h.MAC))
+	}
+	if h.Note != "" {
+		fields = append(fields, "# "+h.Note)
+	}
+	return fields
+}
+
+// parseHost parses the fields after the "host" keyword of a flat plan line
+// and its comment.
+func parseHost(fields []string, comment string) (string, Host, error) {
+	if len(fields) == 0 {
+		return "", Host{}, fmt.Errorf("want \"host <address> [hostname=name] [mac=address] [# note]\"")
+	}
+	labels, metadata, err := parseFields(fields[1:])
+	if err != nil {
+		return "", Host{}, err
+	}
+	if len(labels) > 0 {
+		return "", Host{}, fmt.Errorf("unknown host field %q", labels[0])
+	}
+	h := Host{Note: strings.TrimSpace(strings.TrimPrefix(comment, "#"))}
+	for k, v := range metadata {
+		switch k {
+		case "hostname":
+			h.Hostname = v
+		case "mac":
+			h.MAC = v
+		default:
+			return "", Host{}, fmt.Errorf("unknown host field %q", k)
+		}
+	}
+	return fields[0], h, nil
+}
@@ -0,0 +1,107 @@
+package subnet
+
+import (
+	"testing"
+)
+
+func TestSetHost(t *testing.T) {
+	testCases := []struct {
+		cidr    string
+		address string
+		wantErr bool
+	}{
+		{"10.0.0.0/29", "10.0.0.1", false},
+		{"10.0.0.0/29", "10.0.0.6", false},
+		{"10.0.0.0/29", "10.0.0.0", true},
+		{"10.0.0.0/29", "10.0.0.7", true},
+		{"10.0.0.0/29", "10.0.0.8", true},
+		{"10.0.0.0/29", "10.0.0", true},
+		{"10.0.0.0/31", "10.0.0.0", false},
+		{"10.0.0.5/32", "10.0.0.5", false},
+	}
+	for _, tc := range testCases {
+		address, maskLen, _ := ParseCIDR(tc.cidr)
+		n := &Subnet{Address: address, MaskLen: maskLen}
+		err := n.SetHost(tc.address, Host{Hostname: "h"})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s SetHost(%s) = %v; want error %t", tc.cidr, tc.address, err, tc.wantErr)
+		}
+	}
+
+	n := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	n.Divide()
+	if err := n.SetHost("10.0.0.1", Host{}); err == nil {
+		t.Errorf("SetHost on a divided subnet succeeded; want error")
+	}
+}
+
+func TestNextFreeHost(t *testing.T) {
+	n := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 30}
+	for _, want := range []string{"10.0.0.1", "10.0.0.2"} {
+		address, err := n.NextFreeHost()
+		if err != nil || address != want {
+			t.Fatalf("NextFreeHost() = %q, %v; want %s", address, err, want)
+		}
+		n.SetHost(address, Host{Note: "taken"})
+	}
+	if address, err := n.NextFreeHost(); err == nil {
+		t.Errorf("NextFreeHost() in a full /30 = %q; want error", address)
+	}
+	if err := n.RemoveHost("10.0.0.1"); err != nil {
+		t.Fatalf("RemoveHost returned error: %v", err)
+	}
+	if address, _ := n.NextFreeHost(); address != "10.0.0.1" {
+		t.Errorf("NextFreeHost() after RemoveHost = %q; want 10.0.0.1", address)
+	}
+	if err := n.RemoveHost("10.0.0.1"); err == nil {
+		t.Errorf("RemoveHost of a free address succeeded; want error")
+	}
+}
+
+func TestDivideMovesHosts(t *testing.T) {
+	n := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	n.SetHost("10.0.0.5", Host{Hostname: "web1"})
+	n.SetHost("10.0.0.200", Host{Hostname: "db1"})
+	if err := n.CanDivide(); err != nil {
+		t.Fatalf("CanDivide() = %v; want nil", err)
+	}
+	n.Divide()
+	if n.Hosts != nil || n.Left.Hosts["10.0.0.5"].Hostname != "web1" || n.Right.Hosts["10.0.0.200"].Hostname != "db1" || len(n.Left.Hosts) != 1 || len(n.Right.Hosts) != 1 {
+		t.Errorf("Divide left hosts %v, %v, %v; want one host in each half", n.Hosts, n.Left.Hosts, n.Right.Hosts)
+	}
+	if err := n.CanDivide(); err == nil {
+		t.Errorf("CanDivide() of a divided subnet succeeded; want error")
+	}
+
+	// 10.0.0.127 would be the broadcast address of 10.0.0.0/25.
+	n = &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	n.SetHost("10.0.0.127", Host{})
+	if err := n.CanDivide(); err == nil {
+		t.Errorf("CanDivide() with a host on a half's broadcast address succeeded; want error")
+	}
+	if _, err := n.DivideTo(InetAton("10.0.0.0"), 26); err == nil || n.Left != nil {
+		t.Errorf("DivideTo with a host on a half's broadcast address = %v; want error and no division", err)
+	}
+}
+
+func TestJoinMovesHosts(t *testing.T) {
+	n := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	n.SetHost("10.0.0.5", Host{Hostname: "web1"})
+	n.Divide()
+	n.Right.SetHost("10.0.0.200", Host{Hostname: "db1"})
+	if err := n.CanJoin(); err != nil {
+		t.Fatalf("CanJoin() = %v; want nil", err)
+	}
+	n.Join()
+	if len(n.Hosts) != 2 || n.Hosts["10.0.0.5"].Hostname != "web1" || n.Hosts["10.0.0.200"].Hostname != "db1" {
+		t.Errorf("Join left hosts %v; want both hosts in the joined subnet", n.Hosts)
+	}
+	if err := n.CanJoin(); err == nil {
+		t.Errorf("CanJoin() of a leaf succeeded; want error")
+	}
+	n.Divide()
+	n.Left.Labels = []string{"web"}
+	if err := n.CanJoin(); err == nil {
+		t.Errorf("CanJoin() with a labelled half succeeded; want error")
+	}
+}
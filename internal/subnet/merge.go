@@ -0,0 +1,229 @@
+package subnet
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Conflict is a subnet both sides of a merge changed incompatibly. The
+// merged tree keeps our side of every conflict.
+type Conflict struct {
+	CIDR   string
+	Reason string
+}
+
+func (c Conflict) String() string {
+	return c.CIDR + ": " + c.Reason
+}
+
+// Equal reports whether two trees have the same divisions, labels, metadata,
+// hosts and usage.
+func Equal(a, b *Subnet) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Address == b.Address && a.MaskLen == b.MaskLen &&
+		sameAttributes(a, b) && sameUsed(a.InUse, b.InUse) && Equal(a.Left, b.Left) && Equal(a.Right, b.Right)
+}
+
+// sameUsed reports whether two usage counts are both untracked or equal.
+func sameUsed(a, b *uint64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameAttributes reports whether two nodes have the same labels, metadata
+// and hosts.
+func sameAttributes(a, b *Subnet) bool {
+	return slices.Equal(a.Labels, b.Labels) && maps.Equal(a.Metadata, b.Metadata) && maps.Equal(a.Hosts, b.Hosts)
+}
+
+// Merge combines the changes ours and theirs each made to base. Divisions,
+// joins and relabelling in different parts of the tree are all kept. Where
+// both sides changed the same subnet differently, for example one side
+// joined what the other divided further, or both set different labels, the
+// result keeps ours and the subnet is reported as a conflict.
+func Merge(base, ours, theirs *Subnet) (*Subnet, []Conflict) {
+	if ours.Address != theirs.Address || ours.MaskLen != theirs.MaskLen {
+		return ours.Clone(), []Conflict{{ours.CIDR(), fmt.Sprintf("root differs from %s", theirs.CIDR())}}
+	}
+	if base == nil || base.Address != ours.Address || base.MaskLen != ours.MaskLen {
+		base = &Subnet{Address: ours.Address, MaskLen: ours.MaskLen}
+	}
+	var conflicts []Conflict
+	merged := merge(base, ours, theirs, &conflicts)
+	merged.Parent = nil
+	return merged, conflicts
+}
+
+// merge returns the merged copy of the node at one prefix. base is nil when
+// the base tree was not divided down to this prefix, which is treated as an
+// unlabelled leaf.
+func merge(base, ours, theirs *Subnet, conflicts *[]Conflict) *Subnet {
+	if base == nil {
+		base = &Subnet{Address: ours.Address, MaskLen: ours.MaskLen}
+	}
+	switch {
+	case Equal(ours, theirs), Equal(base, theirs):
+		return ours.Clone()
+	case Equal(base, ours):
+		return theirs.Clone()
+	}
+
+	oursLeaf := ours.Left == nil && ours.Right == nil
+	theirsLeaf := theirs.Left == nil && theirs.Right == nil
+	baseLeaf := base.Left == nil && base.Right == nil
+	switch {
+	case oursLeaf && theirsLeaf:
+		n := &Subnet{Address: ours.Address, MaskLen: ours.MaskLen}
+		mergeAttributes(n, base, ours, theirs, conflicts)
+		return n
+	case !oursLeaf && !theirsLeaf:
+		n := &Subnet{Address: ours.Address, MaskLen: ours.MaskLen}
+		mergeAttributes(n, base, ours, theirs, conflicts)
+		var baseLeft, baseRight *Subnet
+		if !baseLeaf {
+			baseLeft, baseRight = base.Left, base.Right
+		}
+		n.Left = merge(baseLeft, ours.Left, theirs.Left, conflicts)
+		n.Right = merge(baseRight, ours.Right, theirs.Right, conflicts)
+		n.Left.Parent, n.Right.Parent = n, n
+		return n
+	case oursLeaf && baseLeaf:
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), "relabelled here, divided by theirs"})
+	case oursLeaf:
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), "joined here, changed inside by theirs"})
+	case baseLeaf:
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), "divided here, relabelled by theirs"})
+	default:
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), "changed inside here, joined by theirs"})
+	}
+	return ours.Clone()
+}
+
+// mergeAttributes sets the labels, metadata, hosts and usage of n from a
+// three-way merge. Labels and usage merge as a whole; metadata and hosts
+// merge key by key.
+func mergeAttributes(n, base, ours, theirs *Subnet, conflicts *[]Conflict) {
+	switch {
+	case slices.Equal(ours.Labels, theirs.Labels), slices.Equal(base.Labels, theirs.Labels):
+		n.Labels = slices.Clone(ours.Labels)
+	case slices.Equal(base.Labels, ours.Labels):
+		n.Labels = slices.Clone(theirs.Labels)
+	default:
+		n.Labels = slices.Clone(ours.Labels)
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), fmt.Sprintf("labels %v here, %v in theirs", ours.Labels, theirs.Labels)})
+	}
+
+	var conflicting []string
+	n.Metadata, conflicting = mergeMaps(base.Metadata, ours.Metadata, theirs.Metadata)
+	for _, k := range conflicting {
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), fmt.Sprintf("metadata %s=%q here, %q in theirs", k, ours.Metadata[k], theirs.Metadata[k])})
+	}
+	n.Hosts, conflicting = mergeMaps(base.Hosts, ours.Hosts, theirs.Hosts)
+	for _, k := range conflicting {
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), fmt.Sprintf("host %s changed here and in theirs", k)})
+	}
+
+	used := ours.InUse
+	switch {
+	case sameUsed(ours.InUse, theirs.InUse), sameUsed(base.InUse, theirs.InUse):
+	case sameUsed(base.InUse, ours.InUse):
+		used = theirs.InUse
+	default:
+		*conflicts = append(*conflicts, Conflict{ours.CIDR(), "usage changed here and in theirs"})
+	}
+	if used != nil {
+		u := *used
+		n.InUse = &u
+	}
+}
+
+// mergeMaps merges three versions of a map key by key. Keys both sides
+// changed differently keep our value and are returned in order.
+func mergeMaps[V comparable](base, ours, theirs map[string]V) (map[string]V, []string) {
+	keys := make(map[string]string)
+	for _, m := range []map[string]V{base, ours, theirs} {
+		for k := range m {
+			keys[k] = k
+		}
+	}
+	var merged map[string]V
+	var conflicting []string
+	for _, k := range SortedKeys(keys) {
+		b, inBase := base[k]
+		o, inOurs := ours[k]
+		t, inTheirs := theirs[k]
+		value, keep := o, inOurs
+		switch {
+		case inOurs == inTheirs && o == t, inBase == inTheirs && b == t:
+		case inBase == inOurs && b == o:
+			value, keep = t, inTheirs
+		default:
+			conflicting = append(conflicting, k)
+		}
+		if keep {
+			if merged == nil {
+				merged = make(map[string]V)
+			}
+			merged[k] = value
+		}
+	}
+	return merged, conflicting
+}
+
+// MergeWorkspaces merges each root as Merge does. A root added by one side is
+// kept unless it overlaps a root of the other side; a root removed by one
+// side is dropped unless the other side changed it. Either case is otherwise
+// a conflict, resolved by keeping ours.
+func MergeWorkspaces(base, ours, theirs *Workspace) (*Workspace, []Conflict) {
+	var conflicts []Conflict
+	byCIDR := func(ws *Workspace) map[string]*Subnet {
+		roots := make(map[string]*Subnet)
+		if ws != nil {
+			for _, root := range ws.Roots {
+				roots[root.CIDR()] = root
+			}
+		}
+		return roots
+	}
+	baseRoots, ourRoots, theirRoots := byCIDR(base), byCIDR(ours), byCIDR(theirs)
+
+	merged := &Workspace{}
+	for _, o := range ours.Roots {
+		b, t := baseRoots[o.CIDR()], theirRoots[o.CIDR()]
+		switch {
+		case t != nil:
+			root, c := Merge(b, o, t)
+			conflicts = append(conflicts, c...)
+			merged.Roots = append(merged.Roots, root)
+		case b != nil && Equal(b, o):
+			// Removed by theirs.
+		default:
+			if b != nil {
+				conflicts = append(conflicts, Conflict{o.CIDR(), "changed here, removed by theirs"})
+			}
+			merged.Roots = append(merged.Roots, o.Clone())
+		}
+	}
+	for _, t := range theirs.Roots {
+		if ourRoots[t.CIDR()] != nil {
+			continue
+		}
+		b := baseRoots[t.CIDR()]
+		switch {
+		case b != nil && Equal(b, t):
+			// Removed by ours.
+		case b != nil:
+			conflicts = append(conflicts, Conflict{t.CIDR(), "removed here, changed by theirs"})
+		default:
+			if err := merged.AddTree(t.Clone()); err != nil {
+				conflicts = append(conflicts, Conflict{t.CIDR(), "added by theirs, " + err.Error()})
+			}
+		}
+	}
+	return merged, conflicts
+}
@@ -0,0 +1,148 @@
+package subnet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// flatPlan reads a plan in the flat format, failing the test on error.
+func flatPlan(t *testing.T, plan string) *Subnet {
+	t.Helper()
+	root, err := ReadFlat(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("ReadFlat(%q) returned error: %v", plan, err)
+	}
+	return root
+}
+
+func TestMerge(t *testing.T) {
+	base := `10.0.0.0/17
+10.0.128.0/17 web
+`
+	testCases := []struct {
+		name      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts []string
+	}{
+		{
+			name:   "divisions in disjoint branches",
+			ours:   "10.0.0.0/18 a\n10.0.64.0/18\n10.0.128.0/17 web\n",
+			theirs: "10.0.0.0/17\n10.0.128.0/18 web\n10.0.192.0/18 b\n",
+			want:   "10.0.0.0/18 a\n10.0.64.0/18\n10.0.128.0/18 web\n10.0.192.0/18 b\n",
+		},
+		{
+			name:   "both divide the same subnet differently",
+			ours:   "10.0.0.0/18 a\n10.0.64.0/18\n10.0.128.0/17 web\n",
+			theirs: "10.0.0.0/18\n10.0.64.0/19 b\n10.0.96.0/19\n10.0.128.0/17 web\n",
+			want:   "10.0.0.0/18 a\n10.0.64.0/19 b\n10.0.96.0/19\n10.0.128.0/17 web\n",
+		},
+		{
+			name:      "labels changed on both sides",
+			ours:      "10.0.0.0/17\n10.0.128.0/17 web prod\n",
+			theirs:    "10.0.0.0/17\n10.0.128.0/17 api\n",
+			want:      "10.0.0.0/17\n10.0.128.0/17 web prod\n",
+			conflicts: []string{"10.0.128.0/17"},
+		},
+		{
+			name:   "metadata keys merge separately",
+			ours:   "10.0.0.0/17\n10.0.128.0/17 web az=a\n",
+			theirs: "10.0.0.0/17\n10.0.128.0/17 web vpc=main\n",
+			want:   "10.0.0.0/17\n10.0.128.0/17 web az=a vpc=main\n",
+		},
+		{
+			name:      "joined here, divided there",
+			ours:      "10.0.0.0/16\n",
+			theirs:    "10.0.0.0/18 a\n10.0.64.0/18\n10.0.128.0/17 web\n",
+			want:      "10.0.0.0/16\n",
+			conflicts: []string{"10.0.0.0/16"},
+		},
+		{
+			name:      "relabelled here, divided there",
+			ours:      "10.0.0.0/17 db\n10.0.128.0/17 web\n",
+			theirs:    "10.0.0.0/18 a\n10.0.64.0/18\n10.0.128.0/17 web\n",
+			want:      "10.0.0.0/17 db\n10.0.128.0/17 web\n",
+			conflicts: []string{"10.0.0.0/17"},
+		},
+		{
+			name:   "hosts merge separately",
+			ours:   "10.0.0.0/17\n10.0.128.0/17 web\n  host 10.0.128.5 hostname=a\n",
+			theirs: "10.0.0.0/17\n10.0.128.0/17 web\n  host 10.0.128.9 hostname=b\n",
+			want:   "10.0.0.0/17\n10.0.128.0/17 web\n  host 10.0.128.5 hostname=a\n  host 10.0.128.9 hostname=b\n",
+		},
+		{
+			name:      "host changed on both sides",
+			ours:      "10.0.0.0/17\n10.0.128.0/17 web\n  host 10.0.128.5 hostname=a\n",
+			theirs:    "10.0.0.0/17\n10.0.128.0/17 web\n  host 10.0.128.5 hostname=b\n",
+			want:      "10.0.0.0/17\n10.0.128.0/17 web\n  host 10.0.128.5 hostname=a\n",
+			conflicts: []string{"10.0.128.0/17"},
+		},
+		{
+			name:   "usage set there",
+			ours:   "10.0.0.0/17 db\n10.0.128.0/17 web\n",
+			theirs: "10.0.0.0/17\n10.0.128.0/17 web used=40\n",
+			want:   "10.0.0.0/17 db\n10.0.128.0/17 web used=40\n",
+		},
+		{
+			name:      "usage set on both sides",
+			ours:      "10.0.0.0/17\n10.0.128.0/17 web used=30\n",
+			theirs:    "10.0.0.0/17\n10.0.128.0/17 web used=40\n",
+			want:      "10.0.0.0/17\n10.0.128.0/17 web used=30\n",
+			conflicts: []string{"10.0.128.0/17"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, conflicts := Merge(flatPlan(t, base), flatPlan(t, tc.ours), flatPlan(t, tc.theirs))
+			var buf bytes.Buffer
+			WriteFlat(&buf, merged)
+			if buf.String() != tc.want {
+				t.Errorf("Merge =\n%s\nwant\n%s", buf.String(), tc.want)
+			}
+			var cidrs []string
+			for _, c := range conflicts {
+				cidrs = append(cidrs, c.CIDR)
+			}
+			if strings.Join(cidrs, " ") != strings.Join(tc.conflicts, " ") {
+				t.Errorf("Merge conflicts = %v; want %v", conflicts, tc.conflicts)
+			}
+			merged.Iterate(func(n *Subnet) {
+				for p := n; p.Parent != nil; p = p.Parent {
+					if p.Parent.Left != p && p.Parent.Right != p {
+						t.Errorf("%s has a wrong parent link", p.CIDR())
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestMergeWorkspaces(t *testing.T) {
+	workspace := func(plans ...string) *Workspace {
+		ws := &Workspace{}
+		for _, plan := range plans {
+			if err := ws.AddTree(flatPlan(t, plan)); err != nil {
+				t.Fatalf("AddTree returned error: %v", err)
+			}
+		}
+		return ws
+	}
+	base := workspace("10.0.0.0/8\n", "172.16.0.0/12\n", "192.168.0.0/16\n")
+	ours := workspace("10.0.0.0/9 a\n10.128.0.0/9\n", "172.16.0.0/12\n", "198.51.100.0/24\n")
+	theirs := workspace("10.0.0.0/8\n", "192.168.0.0/16 lab\n", "198.51.0.0/16\n", "203.0.113.0/24\n")
+
+	merged, conflicts := MergeWorkspaces(base, ours, theirs)
+	if got, want := roots(merged), "10.0.0.0/8 198.51.100.0/24 203.0.113.0/24"; got != want {
+		t.Errorf("merged roots = %s; want %s", got, want)
+	}
+	var got []string
+	for _, c := range conflicts {
+		got = append(got, c.CIDR)
+	}
+	if want := []string{"192.168.0.0/16", "198.51.0.0/16"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("conflicts = %v; want %v", conflicts, want)
+	}
+}
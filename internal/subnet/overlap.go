@@ -0,0 +1,38 @@
+package subnet
+
+// Overlap is a pair of subnets from two trees whose address ranges overlap,
+// A from the first tree and B from the second.
+type Overlap struct {
+	A, B *Subnet
+}
+
+// Overlapping reports whether the prefixes of two nodes overlap.
+func Overlapping(a, b *Subnet) bool {
+	return a.Contains(b.Address, b.MaskLen) || b.Contains(a.Address, a.MaskLen)
+}
+
+// Overlaps returns every pair of overlapping leaves of two trees, in address
+// order. It is empty when the roots do not overlap.
+func Overlaps(a, b *Subnet) []Overlap {
+	var overlaps []Overlap
+	if !Overlapping(a, b) {
+		return overlaps
+	}
+	var as, bs []*Subnet
+	a.Iterate(func(n *Subnet) { as = append(as, n) })
+	b.Iterate(func(n *Subnet) { bs = append(bs, n) })
+
+	// The leaves of each tree are disjoint and sorted, so a single pass
+	// finds every overlapping pair.
+	for i, j := 0, 0; i < len(as) && j < len(bs); {
+		if Overlapping(as[i], bs[j]) {
+			overlaps = append(overlaps, Overlap{as[i], bs[j]})
+		}
+		if SubnetLastAddress(as[i].Address, as[i].MaskLen) < SubnetLastAddress(bs[j].Address, bs[j].MaskLen) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return overlaps
+}
@@ -0,0 +1,46 @@
+package subnet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverlaps(t *testing.T) {
+	a := flatPlan(t, "10.0.0.0/17 web\n10.0.128.0/18 db\n10.0.192.0/18\n")
+	testCases := []struct {
+		name string
+		b    string
+		want []string
+	}{
+		{
+			name: "disjoint roots",
+			b:    "10.1.0.0/16 other\n",
+		},
+		{
+			name: "nested root",
+			b:    "10.0.128.0/19 x\n10.0.160.0/19 y\n",
+			want: []string{"10.0.128.0/18-10.0.128.0/19", "10.0.128.0/18-10.0.160.0/19"},
+		},
+		{
+			name: "covering root",
+			b:    "10.0.0.0/15 big\n",
+			want: []string{"10.0.0.0/17-10.0.0.0/15", "10.0.128.0/18-10.0.0.0/15", "10.0.192.0/18-10.0.0.0/15"},
+		},
+		{
+			name: "same root, different divisions",
+			b:    "10.0.0.0/16 all\n",
+			want: []string{"10.0.0.0/17-10.0.0.0/16", "10.0.128.0/18-10.0.0.0/16", "10.0.192.0/18-10.0.0.0/16"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, o := range Overlaps(a, flatPlan(t, tc.b)) {
+				got = append(got, o.A.CIDR()+"-"+o.B.CIDR())
+			}
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Overlaps() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package subnet
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Query is a parsed query expression, a predicate on subnets such as
+//
+//	env=prod AND role in (web,api) AND prefix<=24
+//	free AND within 10.20.0.0/16
+//
+// Terms are combined with AND, OR, NOT and parentheses; a comma outside
+// parentheses is an AND, so every Selector is also a query. A term is one of:
+//
+//   - key=value or key!=value, comparing metadata as text;
+//   - key<n, key<=n, key>n or key>=n, comparing metadata as a number;
+//   - key in (a,b,...), matching metadata against a list;
+//   - label=x, label!=x or label in (x,y), testing labels;
+//   - prefix, size, used, util or hosts with any operator, comparing the
+//     mask length, number of addresses, addresses in use, percentage in use
+//     or number of assigned hosts;
+//   - free, leaf, private or public, matching unallocated leaves, undivided
+//     subnets, subnets in RFC 1918 space and subnets outside every
+//     special-purpose block;
+//   - within <cidr> or contains <cidr|address>;
+//   - any other word, matching subnets with that label.
+//
+// Keywords and operators are case insensitive; labels and values are not.
+// Values holding spaces or operators can be double quoted.
+type Query struct {
+	src   string
+	match func(n *Subnet) bool
+}
+
+// ParseQuery parses a query expression.
+func ParseQuery(s string) (*Query, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("query %q: %w", s, err)
+	}
+	p := &queryParser{tokens: tokens}
+	match, err := p.or(true)
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query %q: %w", s, err)
+	}
+	return &Query{s, match}, nil
+}
+
+// Matches reports whether n satisfies the query.
+func (q *Query) Matches(n *Subnet) bool {
+	return q.match(n)
+}
+
+// String returns the query as it was parsed.
+func (q *Query) String() string {
+	return q.src
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// is reports whether t is the keyword word, ignoring case.
+func (t token) is(word string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+// lexQuery splits a query into tokens.
+func lexQuery(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case strings.ContainsRune("=!<>", rune(c)):
+			op := s[i : i+1]
+			if i+1 < len(s) && s[i+1] == '=' {
+				op = s[i : i+2]
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected \"!\" at %d", i)
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokenString, s[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n(),=!<>\"", rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, s[start:i], start})
+		}
+	}
+	return append(tokens, token{tokenEOF, "end of query", len(s)}), nil
+}
+
+// queryParser is a recursive descent parser over the tokens of a query.
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s at %d", fmt.Sprintf(format, args...), p.peek().pos)
+}
+
+// or parses terms joined by OR, and by commas at the top level.
+func (p *queryParser) or(top bool) (func(*Subnet) bool, error) {
+	match, err := p.and(top)
+	for err == nil && p.peek().is("OR") {
+		p.next()
+		var right func(*Subnet) bool
+		right, err = p.and(top)
+		left := match
+		match = func(n *Subnet) bool { return left(n) || right(n) }
+	}
+	return match, err
+}
+
+func (p *queryParser) and(top bool) (func(*Subnet) bool, error) {
+	match, err := p.not()
+	for err == nil && (p.peek().is("AND") || top && p.peek().kind == tokenComma) {
+		p.next()
+		var right func(*Subnet) bool
+		right, err = p.not()
+		left := match
+		match = func(n *Subnet) bool { return left(n) && right(n) }
+	}
+	return match, err
+}
+
+func (p *queryParser) not() (func(*Subnet) bool, error) {
+	if p.peek().is("NOT") {
+		p.next()
+		match, err := p.not()
+		return func(n *Subnet) bool { return !match(n) }, err
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		match, err := p.or(false)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRParen {
+			return nil, p.errorf("missing \")\"")
+		}
+		p.next()
+		return match, nil
+	}
+	return p.term()
+}
+
+// term parses a single comparison, keyword or label.
+func (p *queryParser) term() (func(*Subnet) bool, error) {
+	t := p.peek()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return nil, p.errorf("unexpected %q", t.text)
+	}
+	p.next()
+	name := t.text
+
+	switch {
+	case p.peek().kind == tokenOp:
+		op := p.next().text
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return comparison(name, op, value)
+	case p.peek().is("IN"):
+		p.next()
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		if name == "label" {
+			return func(n *Subnet) bool {
+				return slices.ContainsFunc(values, func(v string) bool { return slices.Contains(n.Labels, v) })
+			}, nil
+		}
+		return func(n *Subnet) bool {
+			v, ok := n.Metadata[name]
+			return ok && slices.Contains(values, v)
+		}, nil
+	case t.kind == tokenString:
+		return hasLabel(name), nil
+	}
+
+	switch strings.ToLower(name) {
+	case "free":
+		return (*Subnet).IsFree, nil
+	case "leaf":
+		return func(n *Subnet) bool { return n.Left == nil && n.Right == nil }, nil
+	case "private":
+		return func(n *Subnet) bool { return Classify(n.Address, n.MaskLen).Private() }, nil
+	case "public":
+		return func(n *Subnet) bool { return Classify(n.Address, n.MaskLen).Public() }, nil
+	case "within", "contains":
+		prefix := p.peek()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		address, maskLen, err := ParseCIDR(value)
+		if ip, ipErr := ParseIP(value); err != nil && ipErr == nil {
+			address, maskLen, err = ip, 32, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v at %d", err, prefix.pos)
+		}
+		if strings.EqualFold(name, "within") {
+			outer := &Subnet{Address: NetworkAddress(address, maskLen), MaskLen: maskLen}
+			return func(n *Subnet) bool { return outer.Contains(n.Address, n.MaskLen) }, nil
+		}
+		return func(n *Subnet) bool { return n.Contains(address, maskLen) }, nil
+	}
+	return hasLabel(name), nil
+}
+
+// value parses a word or quoted string.
+func (p *queryParser) value() (string, error) {
+	t := p.peek()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return "", p.errorf("want a value, got %q", t.text)
+	}
+	p.next()
+	return t.text, nil
+}
+
+// list parses a parenthesised, comma separated list of values.
+func (p *queryParser) list() ([]string, error) {
+	if p.peek().kind != tokenLParen {
+		return nil, p.errorf("want \"(\" after IN")
+	}
+	p.next()
+	var values []string
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		switch p.peek().kind {
+		case tokenRParen:
+			p.next()
+			return values, nil
+		case tokenComma:
+			p.next()
+		default:
+			return nil, p.errorf("want \",\" or \")\"")
+		}
+	}
+}
+
+func hasLabel(label string) func(*Subnet) bool {
+	return func(n *Subnet) bool { return slices.Contains(n.Labels, label) }
+}
+
+// numericFields are the computed fields queries can compare.
+var numericFields = map[string]func(n *Subnet) (float64, bool){
+	"prefix": func(n *Subnet) (float64, bool) { return float64(n.MaskLen), true },
+	"size":   func(n *Subnet) (float64, bool) { return float64(n.Size()), true },
+	"used": func(n *Subnet) (float64, bool) {
+		used, ok := n.Used()
+		return float64(used), ok
+	},
+	"util": func(n *Subnet) (float64, bool) {
+		util, ok := n.Utilization()
+		return 100 * util, ok
+	},
+	"hosts": func(n *Subnet) (float64, bool) { return float64(len(n.Hosts)), true },
+}
+
+// comparisons are the numeric comparison operators.
+var comparisons = map[string]func(a, b float64) bool{
+	"=":  func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+}
+
+// comparison returns the predicate for "name op value". Computed fields are
+// compared as numbers, metadata as strings for = and != and as numbers
+// otherwise.
+func comparison(name, op, value string) (func(*Subnet) bool, error) {
+	compare := comparisons[op]
+	if compare == nil {
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+
+	if name == "label" {
+		has := hasLabel(value)
+		switch op {
+		case "=":
+			return has, nil
+		case "!=":
+			return func(n *Subnet) bool { return !has(n) }, nil
+		}
+		return nil, fmt.Errorf("labels cannot be compared with %q", op)
+	}
+
+	if field, ok := numericFields[strings.ToLower(name)]; ok {
+		want, err := strconv.ParseFloat(strings.TrimPrefix(value, "/"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s %s: want a number", name, op, value)
+		}
+		return func(n *Subnet) bool {
+			got, ok := field(n)
+			return ok && compare(got, want)
+		}, nil
+	}
+
+	switch op {
+	case "=":
+		return func(n *Subnet) bool {
+			v, ok := n.Metadata[name]
+			return ok && v == value
+		}, nil
+	case "!=":
+		return func(n *Subnet) bool {
+			v, ok := n.Metadata[name]
+			return !ok || v != value
+		}, nil
+	}
+	want, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s %s: want a number", name, op, value)
+	}
+	return func(n *Subnet) bool {
+		got, err := strconv.ParseFloat(n.Metadata[name], 64)
+		return err == nil && compare(got, want)
+	}, nil
+}
@@ -0,0 +1,86 @@
+package subnet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	plan := `root 10.20.0.0/16
+10.20.0.0/24 web env=prod vlan=10
+10.20.1.0/24 api env=prod vlan=11 used=200
+10.20.2.0/23 db env=prod role=db
+10.20.4.0/22 web env=dev
+10.20.8.0/21
+10.20.16.0/20
+10.20.32.0/19
+10.20.64.0/18
+10.20.128.0/17
+root 44.0.0.0/24
+44.0.0.0/25 edge role=web
+44.0.0.128/25
+`
+	ws, err := ReadFlatWorkspace(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"env=prod AND label in (web,api) AND prefix<=24", "10.20.0.0/24 10.20.1.0/24"},
+		{"free AND within 10.20.0.0/16", "10.20.8.0/21 10.20.16.0/20 10.20.32.0/19 10.20.64.0/18 10.20.128.0/17"},
+		{"free and prefix >= 20", "10.20.8.0/21 10.20.16.0/20 44.0.0.128/25"},
+		{"env=prod,role=db", "10.20.2.0/23"},
+		{"web OR role in (web, db)", "10.20.0.0/24 10.20.2.0/23 10.20.4.0/22 44.0.0.0/25"},
+		{"NOT (private OR free)", "44.0.0.0/25"},
+		{"public", "44.0.0.0/25 44.0.0.128/25"},
+		{"vlan > 10", "10.20.1.0/24"},
+		{"env != prod AND NOT free", "10.20.4.0/22 44.0.0.0/25"},
+		{"util >= 75", "10.20.1.0/24"},
+		{"contains 10.20.2.7", "10.20.2.0/23"},
+		{`"edge" or label=api`, "10.20.1.0/24 44.0.0.0/25"},
+		{"prefix = /23", "10.20.2.0/23"},
+		{"web env=dev", ""},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery(test.query)
+		if err != nil {
+			if test.want != "" {
+				t.Errorf("ParseQuery(%q) returned error: %v", test.query, err)
+			}
+			continue
+		}
+		var got []string
+		for _, n := range Select(ws, q) {
+			got = append(got, n.CIDR())
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("Select(%q) = %q, want %q", test.query, strings.Join(got, " "), test.want)
+		}
+	}
+
+	for _, bad := range []string{"", "env=", "(web", "web)", "role in web", "prefix <= big", "within 10.0.0.0/33", "web AND", "a ! b", `"open`, "vlan > ten", "label < x"} {
+		if _, err := ParseQuery(bad); err == nil {
+			t.Errorf("ParseQuery(%q) returned no error", bad)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	ws, err := ReadFlatWorkspace(strings.NewReader("10.0.0.0/24 web\n10.0.1.0/25 db\n10.0.1.128/25\n"))
+	if err != nil {
+		t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+	}
+	q, err := ParseQuery("db")
+	if err != nil {
+		t.Fatalf("ParseQuery returned error: %v", err)
+	}
+	var got []string
+	Filter(ws, q).Walk(func(n *Subnet) {
+		got = append(got, n.CIDR())
+	})
+	if want := "10.0.0.0/23 10.0.1.0/24 10.0.1.0/25"; strings.Join(got, " ") != want {
+		t.Errorf("Filter(db).Walk = %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,43 @@
+package subnet
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// RangeToCIDRs returns the fewest CIDR blocks, in address order, covering
+// exactly the addresses of r.
+func RangeToCIDRs(r Range) []*Subnet {
+	return Summarize([]Range{r})
+}
+
+// ImportRange divides plan down to the blocks covering r and gives each of
+// them the labels and metadata. Every block must fall inside a free leaf or
+// be one; otherwise nothing is changed. The new leaves are returned in
+// address order.
+func ImportRange(plan Plan, r Range, labels []string, metadata map[string]string) ([]*Subnet, error) {
+	blocks := RangeToCIDRs(r)
+	for _, b := range blocks {
+		n := plan.Locate(b.Address, b.MaskLen)
+		switch {
+		case n == nil:
+			return nil, fmt.Errorf("%s is outside the plan", b.CIDR())
+		case n.MaskLen == b.MaskLen && (n.Left != nil || n.Right != nil):
+			return nil, fmt.Errorf("%s is already divided", b.CIDR())
+		case !n.IsFree():
+			return nil, fmt.Errorf("%s overlaps allocated %s", b.CIDR(), n.CIDR())
+		}
+	}
+	leaves := make([]*Subnet, len(blocks))
+	for i, b := range blocks {
+		leaf, err := plan.DivideTo(b.Address, b.MaskLen)
+		if err != nil {
+			return nil, err
+		}
+		leaf.Labels = slices.Clone(labels)
+		leaf.Metadata = maps.Clone(metadata)
+		leaves[i] = leaf
+	}
+	return leaves, nil
+}
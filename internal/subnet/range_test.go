@@ -0,0 +1,81 @@
+package subnet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRangeToCIDRs(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{"203.0.113.17-203.0.113.200", "203.0.113.17/32 203.0.113.18/31 203.0.113.20/30 203.0.113.24/29 203.0.113.32/27 203.0.113.64/26 203.0.113.128/26 203.0.113.192/29 203.0.113.200/32"},
+		{"10.0.0.0-10.0.255.255", "10.0.0.0/16"},
+		{"10.0.0.255-10.0.1.0", "10.0.0.255/32 10.0.1.0/32"},
+		{"0.0.0.0-255.255.255.255", "0.0.0.0/0"},
+		{"255.255.255.255-255.255.255.255", "255.255.255.255/32"},
+	}
+	for _, tc := range testCases {
+		r, err := ParseRange(tc.input)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) returned error: %v", tc.input, err)
+		}
+		var got []string
+		for _, n := range RangeToCIDRs(r) {
+			got = append(got, n.CIDR())
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("RangeToCIDRs(%s) = %v; want %s", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestImportRange(t *testing.T) {
+	testCases := []struct {
+		name    string
+		plan    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"free root", "10.0.0.0/24", "10.0.0.4-10.0.0.11", "10.0.0.4/30 10.0.0.8/30", false},
+		{"existing leaf", "10.0.0.0/25\n10.0.0.128/25", "10.0.0.0-10.0.0.127", "10.0.0.0/25", false},
+		{"allocated", "10.0.0.0/25 web\n10.0.0.128/25", "10.0.0.100-10.0.0.200", "", true},
+		{"divided", "10.0.0.0/25\n10.0.0.128/26\n10.0.0.192/26", "10.0.0.128-10.0.0.255", "", true},
+		{"outside", "10.0.0.0/24", "10.0.0.200-10.0.1.10", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ws, err := ReadFlatWorkspace(strings.NewReader(tc.plan))
+			if err != nil {
+				t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+			}
+			before := ws.Clone()
+			r, _ := ParseRange(tc.input)
+			leaves, err := ImportRange(ws, r, []string{"vendor"}, map[string]string{"source": "allow-list"})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ImportRange(%s) = %v; want error", tc.input, leaves)
+				}
+				if !Equal(ws.Roots[0], before.Roots[0]) {
+					t.Errorf("ImportRange(%s) changed the plan on error", tc.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImportRange(%s) returned error: %v", tc.input, err)
+			}
+			var got []string
+			for _, leaf := range leaves {
+				got = append(got, leaf.CIDR())
+				if len(leaf.Labels) != 1 || leaf.Metadata["source"] != "allow-list" {
+					t.Errorf("leaf %s has labels %v and metadata %v", leaf.CIDR(), leaf.Labels, leaf.Metadata)
+				}
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Errorf("ImportRange(%s) = %v; want %s", tc.input, got, tc.want)
+			}
+		})
+	}
+}
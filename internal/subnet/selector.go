@@ -0,0 +1,96 @@
+package subnet
+
+import (
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Matcher is a predicate on subnets, such as a Selector or a Query.
+type Matcher interface {
+	Matches(n *Subnet) bool
+	String() string
+}
+
+// Selector picks subnets by their labels and metadata. A subnet matches when
+// it has every label and every metadata key=value pair of the selector;
+// subnets without labels or metadata never match.
+type Selector struct {
+	Labels   []string
+	Metadata map[string]string
+}
+
+// Matches reports whether n has every label and metadata pair of s.
+func (s Selector) Matches(n *Subnet) bool {
+	if len(n.Labels) == 0 && len(n.Metadata) == 0 {
+		return false
+	}
+	for _, label := range s.Labels {
+		if !slices.Contains(n.Labels, label) {
+			return false
+		}
+	}
+	for k, v := range s.Metadata {
+		if got, ok := n.Metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector as a query, such as "web,env=prod".
+func (s Selector) String() string {
+	terms := append([]string{}, s.Labels...)
+	for _, k := range SortedKeys(s.Metadata) {
+		terms = append(terms, k+"="+s.Metadata[k])
+	}
+	return strings.Join(terms, ",")
+}
+
+// Select returns the leaves of the plan matching m, in address order.
+func Select(plan Plan, m Matcher) []*Subnet {
+	return slices.Collect(Leaves(Filter(plan, m)))
+}
+
+// Filter returns a view of the plan holding only the leaves matching m and
+// the nodes above them, so that exporters can write part of a plan. Find,
+// Locate and DivideTo act on the whole plan.
+func Filter(plan Plan, m Matcher) Plan {
+	return filtered{plan, m}
+}
+
+type filtered struct {
+	Plan
+	m Matcher
+}
+
+func (f filtered) Iterate(fn func(*Subnet)) {
+	for n := range Leaves(f) {
+		fn(n)
+	}
+}
+
+func (f filtered) Walk(fn func(*Subnet)) {
+	for n := range f.All(PreOrder) {
+		fn(n)
+	}
+}
+
+func (f filtered) All(order Order) iter.Seq2[*Subnet, int] {
+	return func(yield func(*Subnet, int) bool) {
+		keep := make(map[*Subnet]bool)
+		for n := range Leaves(f.Plan) {
+			if !f.m.Matches(n) {
+				continue
+			}
+			for p := n; p != nil && !keep[p]; p = p.Parent {
+				keep[p] = true
+			}
+		}
+		for n, depth := range f.Plan.All(order) {
+			if keep[n] && !yield(n, depth) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package subnet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	plan := `root 10.0.0.0/22
+10.0.0.0/24 web env=prod
+10.0.1.0/25 db env=prod role=db
+10.0.1.128/25 db env=dev role=db
+10.0.2.0/24
+10.0.3.0/24 web
+`
+	ws, err := ReadFlatWorkspace(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+	}
+	tests := []struct {
+		selector Selector
+		want     string
+	}{
+		{Selector{Metadata: map[string]string{"env": "prod", "role": "db"}}, "10.0.1.0/25"},
+		{Selector{Metadata: map[string]string{"env": "prod"}}, "10.0.0.0/24 10.0.1.0/25"},
+		{Selector{Labels: []string{"web"}}, "10.0.0.0/24 10.0.3.0/24"},
+		{Selector{Labels: []string{"db"}, Metadata: map[string]string{"env": "dev"}}, "10.0.1.128/25"},
+		{Selector{Labels: []string{"web"}, Metadata: map[string]string{"role": "db"}}, ""},
+		{Selector{}, "10.0.0.0/24 10.0.1.0/25 10.0.1.128/25 10.0.3.0/24"},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, n := range Select(ws, test.selector) {
+			got = append(got, n.CIDR())
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("Select(%q) = %q, want %q", test.selector, strings.Join(got, " "), test.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package subnet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpecialBlock is an entry of the IANA IPv4 special-purpose address
+// registries.
+type SpecialBlock struct {
+	Address uint32
+	MaskLen uint32
+	Name    string
+	RFC     string
+	// Private is set for the RFC 1918 private-use blocks.
+	Private bool
+}
+
+// CIDR returns the block in CIDR notation.
+func (b SpecialBlock) CIDR() string {
+	return FormatCIDR(b.Address, b.MaskLen)
+}
+
+func (b SpecialBlock) String() string {
+	return fmt.Sprintf("%s %s (%s)", b.CIDR(), b.Name, b.RFC)
+}
+
+// SpecialBlocks are the special-purpose blocks, sorted by address with a
+// block listed before the blocks inside it.
+var SpecialBlocks = []SpecialBlock{
+	specialBlock("0.0.0.0/8", "this network", "RFC 791", false),
+	specialBlock("10.0.0.0/8", "private-use", "RFC 1918", true),
+	specialBlock("100.64.0.0/10", "shared address space (CGNAT)", "RFC 6598", false),
+	specialBlock("127.0.0.0/8", "loopback", "RFC 1122", false),
+	specialBlock("169.254.0.0/16", "link-local", "RFC 3927", false),
+	specialBlock("172.16.0.0/12", "private-use", "RFC 1918", true),
+	specialBlock("192.0.0.0/24", "IETF protocol assignments", "RFC 6890", false),
+	specialBlock("192.0.2.0/24", "documentation (TEST-NET-1)", "RFC 5737", false),
+	specialBlock("192.31.196.0/24", "AS112-v4", "RFC 7535", false),
+	specialBlock("192.52.193.0/24", "AMT", "RFC 7450", false),
+	specialBlock("192.88.99.0/24", "6to4 relay anycast", "RFC 7526", false),
+	specialBlock("192.168.0.0/16", "private-use", "RFC 1918", true),
+	specialBlock("192.175.48.0/24", "direct delegation AS112", "RFC 7534", false),
+	specialBlock("198.18.0.0/15", "benchmarking", "RFC 2544", false),
+	specialBlock("198.51.100.0/24", "documentation (TEST-NET-2)", "RFC 5737", false),
+	specialBlock("203.0.113.0/24", "documentation (TEST-NET-3)", "RFC 5737", false),
+	specialBlock("224.0.0.0/4", "multicast", "RFC 5771", false),
+	specialBlock("240.0.0.0/4", "reserved", "RFC 1112", false),
+	specialBlock("255.255.255.255/32", "limited broadcast", "RFC 919", false),
+}
+
+func specialBlock(cidr, name, rfc string, private bool) SpecialBlock {
+	address, maskLen, err := ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return SpecialBlock{address, maskLen, name, rfc, private}
+}
+
+// Classification is how a prefix relates to the special-purpose blocks.
+type Classification struct {
+	// Within is the most specific special-purpose block containing the
+	// prefix, if any.
+	Within *SpecialBlock
+	// Overlaps are the special-purpose blocks inside a larger prefix.
+	Overlaps []SpecialBlock
+}
+
+// Classify finds the special-purpose blocks containing or inside a prefix.
+func Classify(address, maskLen uint32) Classification {
+	var c Classification
+	n := &Subnet{Address: NetworkAddress(address, maskLen), MaskLen: maskLen}
+	for i, b := range SpecialBlocks {
+		switch {
+		case (&Subnet{Address: b.Address, MaskLen: b.MaskLen}).Contains(n.Address, n.MaskLen):
+			c.Within = &SpecialBlocks[i]
+		case n.Contains(b.Address, b.MaskLen):
+			c.Overlaps = append(c.Overlaps, b)
+		}
+	}
+	return c
+}
+
+// Private reports whether the prefix is inside RFC 1918 private-use space.
+func (c Classification) Private() bool {
+	return c.Within != nil && c.Within.Private
+}
+
+// Public reports whether the prefix overlaps no special-purpose block.
+func (c Classification) Public() bool {
+	return c.Within == nil && len(c.Overlaps) == 0
+}
+
+func (c Classification) String() string {
+	switch {
+	case c.Private():
+		return "private (" + c.Within.RFC + ")"
+	case c.Within != nil:
+		return c.Within.Name + " (" + c.Within.RFC + ")"
+	case c.Public():
+		return "public"
+	}
+	blocks := make([]string, len(c.Overlaps))
+	for i, b := range c.Overlaps {
+		blocks[i] = b.CIDR() + " " + b.Name
+	}
+	return "public, overlaps " + strings.Join(blocks, ", ")
+}
+
+// ScopeKey is the metadata key marking a subnet and everything inside it as
+// intended for a scope. The only scope checked is "private".
+const ScopeKey = "scope"
+
+// Warning is a problem found in a plan that does not stop it from being used.
+type Warning struct {
+	CIDR    string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.CIDR + ": " + w.Message
+}
+
+// ScopeWarnings returns a warning for every subnet intended to be private
+// that is not inside RFC 1918 space. Subnets are intended private when they
+// or an ancestor have scope=private metadata, or everywhere when private is
+// set. Only the largest such subnet of a branch is reported.
+func ScopeWarnings(plan Plan, private bool) []Warning {
+	var warnings []Warning
+	warned := make(map[*Subnet]bool)
+	plan.Walk(func(n *Subnet) {
+		if n.Parent != nil && warned[n.Parent] {
+			warned[n] = true
+			return
+		}
+		intended := private
+		for p := n; p != nil && !intended; p = p.Parent {
+			intended = p.Metadata[ScopeKey] == "private"
+		}
+		if c := Classify(n.Address, n.MaskLen); intended && !c.Private() {
+			warned[n] = true
+			warnings = append(warnings, Warning{n.CIDR(), "intended private but " + c.String()})
+		}
+	})
+	return warnings
+}
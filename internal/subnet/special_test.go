@@ -0,0 +1,86 @@
+package subnet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		cidr    string
+		want    string
+		private bool
+		public  bool
+	}{
+		{"10.20.0.0/16", "private (RFC 1918)", true, false},
+		{"172.31.255.0/24", "private (RFC 1918)", true, false},
+		{"172.32.0.0/16", "public", false, true},
+		{"100.64.1.0/24", "shared address space (CGNAT) (RFC 6598)", false, false},
+		{"198.51.100.0/25", "documentation (TEST-NET-2) (RFC 5737)", false, false},
+		{"239.1.1.1/32", "multicast (RFC 5771)", false, false},
+		{"192.168.0.0/15", "public, overlaps 192.168.0.0/16 private-use", false, false},
+		{"8.8.8.0/24", "public", false, true},
+		{"0.1.2.0/24", "this network (RFC 791)", false, false},
+		{"192.0.0.8/29", "IETF protocol assignments (RFC 6890)", false, false},
+		{"192.88.99.0/24", "6to4 relay anycast (RFC 7526)", false, false},
+		{"192.31.196.0/25", "AS112-v4 (RFC 7535)", false, false},
+		{"192.52.193.0/24", "AMT (RFC 7450)", false, false},
+		{"192.175.48.1/32", "direct delegation AS112 (RFC 7534)", false, false},
+		{"192.175.49.0/24", "public", false, true},
+		{"198.19.0.0/16", "benchmarking (RFC 2544)", false, false},
+		{"255.255.255.255/32", "limited broadcast (RFC 919)", false, false},
+		{"255.255.255.0/24", "reserved (RFC 1112)", false, false},
+		{"192.0.1.0/24", "public", false, true},
+		{"198.20.0.0/16", "public", false, true},
+	}
+	for _, tc := range testCases {
+		address, maskLen, err := ParseCIDR(tc.cidr)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q) returned error: %v", tc.cidr, err)
+		}
+		c := Classify(address, maskLen)
+		if c.String() != tc.want || c.Private() != tc.private || c.Public() != tc.public {
+			t.Errorf("Classify(%s) = %q private %t public %t; want %q private %t public %t", tc.cidr, c, c.Private(), c.Public(), tc.want, tc.private, tc.public)
+		}
+	}
+}
+
+func TestScopeWarnings(t *testing.T) {
+	ws, err := ReadFlatWorkspace(strings.NewReader(`root 10.0.0.0/8
+10.0.0.0/9 scope=private
+10.128.0.0/9
+root 172.0.0.0/10
+172.0.0.0/12
+172.16.0.0/12 scope=private
+172.32.0.0/11
+root 203.0.113.0/24
+203.0.113.0/24 web
+`))
+	if err != nil {
+		t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+	}
+	var got []string
+	for _, w := range ScopeWarnings(ws, false) {
+		got = append(got, w.CIDR)
+	}
+	if strings.Join(got, " ") != "" {
+		t.Errorf("ScopeWarnings = %v; want none", got)
+	}
+
+	ws.Roots[1].Metadata = map[string]string{ScopeKey: "private"}
+	got = nil
+	for _, w := range ScopeWarnings(ws, false) {
+		got = append(got, w.CIDR)
+	}
+	if want := "172.0.0.0/10"; strings.Join(got, " ") != want {
+		t.Errorf("ScopeWarnings with a private root = %v; want %s", got, want)
+	}
+
+	got = nil
+	for _, w := range ScopeWarnings(ws, true) {
+		got = append(got, w.CIDR)
+	}
+	if want := "172.0.0.0/10 203.0.113.0/24"; strings.Join(got, " ") != want {
+		t.Errorf("ScopeWarnings(private) = %v; want %s", got, want)
+	}
+}
@@ -1,9 +1,7 @@
 package subnet
 
 import (
-	"encoding/json"
-	"os"
-
+	"fmt"
 )
 
 // SubnetNode represents a node in the subnet division tree.
@@ -14,6 +12,14 @@ type Subnet struct {
 	Left    *Subnet
 	Right   *Subnet
 	Labels  []string
+	// Metadata holds free-form key/value attributes such as "vpc" or "az".
+	Metadata map[string]string `json:",omitempty"`
+	// Hosts maps addresses inside a leaf, such as "10.0.0.5", to the hosts
+	// using them.
+	Hosts map[string]Host `json:",omitempty"`
+	// InUse is the number of addresses in use in a leaf, if tracked. It is
+	// kept out of Metadata so that exports and diffs leave it alone.
+	InUse *uint64 `json:",omitempty"`
 }
 
 // divide splits a subnet node into two subnets.
@@ -42,6 +48,11 @@ func (n *Subnet) Divide() {
 		MaskLen: n.MaskLen + 1,
 		Parent:  n,
 	}
+	n.splitHosts()
+	// The usage of a divided subnet is summed from its leaves.
+	if n.InUse != nil && *n.InUse == 0 {
+		n.InUse = nil
+	}
 }
 
 // merge combines two child subnets into their parent subnet.
@@ -51,6 +62,7 @@ func (n *Subnet) Join() {
 	}
 	// Assuming the caller ensures that n is the correct parent of Left and Right,
 	// and they are adjacent, thus can be merged.
+	n.joinHosts()
 	n.Left = nil
 	n.Right = nil
 }
@@ -74,52 +86,151 @@ func (n *Subnet) Find(address uint32, maskLen uint32) *Subnet {
 	return nil // Node not found
 }
 
-// iterate applies a function to each node in the tree.
-func (n *Subnet) Iterate(f func(*Subnet)) {
-	if n.Left == nil && n.Right == nil {
-		f(n) // Apply function only if it's a leaf node
-	} else {
-		// If not a leaf node, recursively iterate over children
-		if n.Left != nil {
-			n.Left.Iterate(f)
+// CIDR returns the subnet in "10.0.0.0/24" notation.
+func (n *Subnet) CIDR() string {
+	return FormatCIDR(n.Address, n.MaskLen)
+}
+
+// Contains reports whether the prefix address/maskLen lies within the subnet.
+func (n *Subnet) Contains(address uint32, maskLen uint32) bool {
+	return maskLen >= n.MaskLen && NetworkAddress(address, n.MaskLen) == n.Address
+}
+
+// Locate returns the most specific node in the tree containing the prefix
+// address/maskLen, or nil if the prefix is outside the tree.
+func (n *Subnet) Locate(address uint32, maskLen uint32) *Subnet {
+	if !n.Contains(address, maskLen) {
+		return nil
+	}
+	for _, child := range []*Subnet{n.Left, n.Right} {
+		if child != nil {
+			if found := child.Locate(address, maskLen); found != nil {
+				return found
+			}
 		}
-		if n.Right != nil {
-			n.Right.Iterate(f)
+	}
+	return n
+}
+
+// DivideTo divides the tree down until the prefix address/maskLen exists as a
+// node and returns that node. Existing divisions along the way are kept.
+func (n *Subnet) DivideTo(address uint32, maskLen uint32) (*Subnet, error) {
+	if NetworkAddress(address, maskLen) != address {
+		return nil, fmt.Errorf("%s is not a network address", FormatCIDR(address, maskLen))
+	}
+	if !n.Contains(address, maskLen) {
+		return nil, fmt.Errorf("%s is outside %s", FormatCIDR(address, maskLen), n.CIDR())
+	}
+	node := n
+	for node.MaskLen < maskLen {
+		if node.Left == nil {
+			if err := node.CanDivide(); err != nil {
+				return nil, err
+			}
+		}
+		node.Divide()
+		if node.Right.Contains(address, maskLen) {
+			node = node.Right
+		} else {
+			node = node.Left
 		}
 	}
+	return node, nil
 }
 
-// SaveTree saves the subnet tree to a file in JSON format.
-func SaveTree(root *Subnet, filename string) error {
-	data, err := json.MarshalIndent(root, "", "  ")
-	if err != nil {
-		return err
+// IsFree reports whether n is an unallocated leaf: undivided, unlabelled,
+// without metadata or hosts and with no addresses in use.
+func (n *Subnet) IsFree() bool {
+	return n.Left == nil && n.Right == nil && len(n.Labels) == 0 && len(n.Metadata) == 0 && len(n.Hosts) == 0 &&
+		(n.InUse == nil || *n.InUse == 0)
+}
+
+// Allocate divides the smallest free leaf that can hold a prefix of maskLen
+// down to that size and returns the new leaf, preferring lower addresses.
+func (n *Subnet) Allocate(maskLen uint32) (*Subnet, error) {
+	if maskLen > 32 {
+		return nil, fmt.Errorf("invalid mask length %d", maskLen)
 	}
-	return os.WriteFile(filename, data, 0644)
+	var best *Subnet
+	n.Iterate(func(leaf *Subnet) {
+		if leaf.IsFree() && leaf.MaskLen <= maskLen && (best == nil || leaf.MaskLen > best.MaskLen) {
+			best = leaf
+		}
+	})
+	if best == nil {
+		return nil, fmt.Errorf("no free /%d left in %s", maskLen, n.CIDR())
+	}
+	return best.DivideTo(best.Address, maskLen)
 }
 
-// LoadTree loads the subnet tree from a file in JSON format.
-func LoadTree(filename string) (*Subnet, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
+// Clone returns a deep copy of the tree rooted at n, with the copy's root
+// detached from any parent.
+func (n *Subnet) Clone() *Subnet {
+	var clone func(n, parent *Subnet) *Subnet
+	clone = func(n, parent *Subnet) *Subnet {
+		if n == nil {
+			return nil
+		}
+		c := &Subnet{Address: n.Address, MaskLen: n.MaskLen, Parent: parent}
+		if n.Labels != nil {
+			c.Labels = append([]string{}, n.Labels...)
+		}
+		if n.Metadata != nil {
+			c.Metadata = make(map[string]string, len(n.Metadata))
+			for k, v := range n.Metadata {
+				c.Metadata[k] = v
+			}
+		}
+		if n.Hosts != nil {
+			c.Hosts = make(map[string]Host, len(n.Hosts))
+			for k, v := range n.Hosts {
+				c.Hosts[k] = v
+			}
+		}
+		if n.InUse != nil {
+			used := *n.InUse
+			c.InUse = &used
+		}
+		c.Left = clone(n.Left, c)
+		c.Right = clone(n.Right, c)
+		return c
 	}
+	return clone(n, nil)
+}
 
-	var root Subnet
-	if err := json.Unmarshal(data, &root); err != nil {
-		return nil, err
+// Iterate calls f for every leaf of the tree in address order.
+func (n *Subnet) Iterate(f func(*Subnet)) {
+	for leaf := range Leaves(n) {
+		f(leaf)
 	}
+}
 
-	reconstructParent(&root, nil) // Reconstruct parent pointers
+// Walk calls f for every node of the tree in address order, parents before
+// their children.
+func (n *Subnet) Walk(f func(*Subnet)) {
+	for m := range n.All(PreOrder) {
+		f(m)
+	}
+}
 
-	return &root, nil
+// SaveTree saves the subnet tree to a file, as SaveWorkspace does for a
+// workspace with that one root.
+func SaveTree(root *Subnet, filename string) error {
+	return SaveWorkspace(&Workspace{Roots: []*Subnet{root}}, filename)
 }
 
-// reconstructParent helps to set the Parent field after loading from JSON.
-func reconstructParent(node *Subnet, parent *Subnet) {
-	if node != nil {
-		node.Parent = parent
-		reconstructParent(node.Left, node)
-		reconstructParent(node.Right, node)
+// LoadTree loads a subnet tree saved with SaveTree, or a workspace file that
+// holds a single root.
+func LoadTree(filename string) (*Subnet, error) {
+	ws, err := LoadWorkspace(filename)
+	if err != nil {
+		return nil, err
 	}
+	return ws.Single()
+}
+
+// reconstructParent sets the Parent field of node and everything below it
+// after loading from JSON.
+func reconstructParent(node *Subnet, parent *Subnet) {
+	normalize(node, parent)
 }
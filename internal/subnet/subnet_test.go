@@ -142,3 +142,99 @@ func TestSubnetDivision(t *testing.T) {
 		})
 	}
 }
+
+func TestDivideTo(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 16}
+
+	node, err := root.DivideTo(InetAton("10.0.3.0"), 24)
+	if err != nil {
+		t.Fatalf("DivideTo returned error: %v", err)
+	}
+	if node.CIDR() != "10.0.3.0/24" {
+		t.Errorf("DivideTo returned %s; want 10.0.3.0/24", node.CIDR())
+	}
+	if root.Find(InetAton("10.0.128.0"), 17) == nil {
+		t.Errorf("DivideTo did not create sibling 10.0.128.0/17")
+	}
+
+	// Dividing to an existing node keeps the tree unchanged.
+	again, err := root.DivideTo(InetAton("10.0.0.0"), 22)
+	if err != nil || again.Left == nil {
+		t.Errorf("DivideTo(10.0.0.0/22) = %v, %v; want existing divided node", again, err)
+	}
+
+	if _, err := root.DivideTo(InetAton("10.1.0.0"), 24); err == nil {
+		t.Errorf("DivideTo outside the root succeeded; want error")
+	}
+	if _, err := root.DivideTo(InetAton("10.0.0.1"), 24); err == nil {
+		t.Errorf("DivideTo with host bits set succeeded; want error")
+	}
+}
+
+func TestLocate(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 16}
+	root.DivideTo(InetAton("10.0.4.0"), 22)
+
+	testCases := []struct {
+		cidr string
+		want string
+	}{
+		{"10.0.5.7/32", "10.0.4.0/22"},
+		{"10.0.4.0/22", "10.0.4.0/22"},
+		{"10.0.200.0/24", "10.0.128.0/17"},
+		{"10.0.0.0/16", "10.0.0.0/16"},
+		{"10.1.0.0/24", ""},
+	}
+	for _, testCase := range testCases {
+		address, maskLen, _ := ParseCIDR(testCase.cidr)
+		got := ""
+		if n := root.Locate(address, maskLen); n != nil {
+			got = n.CIDR()
+		}
+		if got != testCase.want {
+			t.Errorf("Locate(%s) = %q; want %q", testCase.cidr, got, testCase.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 22}
+	web, _ := root.DivideTo(InetAton("10.0.0.0"), 24)
+	web.Labels = []string{"web"}
+
+	// The free /24 at 10.0.1.0 is a better fit than the free /23.
+	got, err := root.Allocate(26)
+	if err != nil || got.CIDR() != "10.0.1.0/26" {
+		t.Fatalf("Allocate(26) = %v, %v; want 10.0.1.0/26", got, err)
+	}
+	got.Labels = []string{"db"}
+
+	got, err = root.Allocate(23)
+	if err != nil || got.CIDR() != "10.0.2.0/23" {
+		t.Fatalf("Allocate(23) = %v, %v; want 10.0.2.0/23", got, err)
+	}
+	got.Labels = []string{"big"}
+
+	if got, err := root.Allocate(24); err == nil {
+		t.Errorf("Allocate(24) = %s; want error when no /24 is free", got.CIDR())
+	}
+}
+
+func TestClone(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	leaf, _ := root.DivideTo(InetAton("10.0.0.128"), 26)
+	leaf.Labels = []string{"web"}
+	leaf.Metadata = map[string]string{"env": "prod"}
+
+	c := root.Clone()
+	cl := c.Find(leaf.Address, leaf.MaskLen)
+	if cl == nil || cl == leaf || cl.Parent.Parent != c || cl.Labels[0] != "web" {
+		t.Fatalf("Clone did not copy 10.0.0.128/26 with its parents and labels")
+	}
+	cl.Labels[0] = "db"
+	cl.Metadata["env"] = "dev"
+	c.Join()
+	if leaf.Labels[0] != "web" || leaf.Metadata["env"] != "prod" || root.Left == nil {
+		t.Errorf("changing the clone changed the original")
+	}
+}
@@ -0,0 +1,100 @@
+package subnet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Range is an inclusive range of IPv4 addresses.
+type Range struct {
+	First, Last uint32
+}
+
+func (r Range) String() string {
+	return InetNtoa(r.First) + "-" + InetNtoa(r.Last)
+}
+
+// ParseRange parses a single address, a CIDR such as "10.0.0.0/24" or a
+// range such as "10.0.0.5-10.0.0.9". Host bits in a CIDR are ignored.
+func ParseRange(s string) (Range, error) {
+	s = strings.ReplaceAll(s, "–", "-")
+	if first, last, ok := strings.Cut(s, "-"); ok {
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		var r Range
+		var err error
+		if r.First, err = ParseIP(first); err == nil {
+			r.Last, err = ParseIP(last)
+		}
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range %q", s)
+		}
+		if r.First > r.Last {
+			return Range{}, fmt.Errorf("invalid range %q: end is before start", s)
+		}
+		return r, nil
+	}
+	if strings.Contains(s, "/") {
+		address, maskLen, err := ParseCIDR(s)
+		if err != nil {
+			return Range{}, err
+		}
+		address = NetworkAddress(address, maskLen)
+		return Range{address, SubnetLastAddress(address, maskLen)}, nil
+	}
+	address, err := ParseIP(s)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid address %q", s)
+	}
+	return Range{address, address}, nil
+}
+
+// Summarize returns the fewest CIDR blocks, in address order, covering
+// exactly the addresses in ranges. Overlapping, contained and adjacent blocks
+// are merged. The blocks are found by dividing 0.0.0.0/0 down to each range
+// and joining every pair of halves that are both covered.
+func Summarize(ranges []Range) []*Subnet {
+	root := &Subnet{}
+	covered := make(map[*Subnet]bool)
+	for _, r := range ranges {
+		cover(root, r, covered)
+	}
+	joinCovered(root, covered)
+
+	var blocks []*Subnet
+	root.Walk(func(n *Subnet) {
+		if covered[n] && (n.Parent == nil || !covered[n.Parent]) {
+			blocks = append(blocks, &Subnet{Address: n.Address, MaskLen: n.MaskLen})
+		}
+	})
+	return blocks
+}
+
+// cover divides n until the nodes inside r can be marked covered.
+func cover(n *Subnet, r Range, covered map[*Subnet]bool) {
+	first, last := n.Address, SubnetLastAddress(n.Address, n.MaskLen)
+	if covered[n] || r.Last < first || r.First > last {
+		return
+	}
+	if r.First <= first && last <= r.Last {
+		n.Join()
+		covered[n] = true
+		return
+	}
+	n.Divide()
+	cover(n.Left, r, covered)
+	cover(n.Right, r, covered)
+}
+
+// joinCovered joins every node whose halves are both covered and reports
+// whether n is covered.
+func joinCovered(n *Subnet, covered map[*Subnet]bool) bool {
+	if covered[n] || n.Left == nil || n.Right == nil {
+		return covered[n]
+	}
+	left, right := joinCovered(n.Left, covered), joinCovered(n.Right, covered)
+	if left && right {
+		n.Join()
+		covered[n] = true
+	}
+	return covered[n]
+}
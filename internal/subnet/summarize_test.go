@@ -0,0 +1,69 @@
+package subnet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{"10.0.0.7", "10.0.0.7-10.0.0.7"},
+		{"10.0.0.7/24", "10.0.0.0-10.0.0.255"},
+		{"10.0.0.5-10.0.0.9", "10.0.0.5-10.0.0.9"},
+		{"203.0.113.17 – 203.0.113.200", "203.0.113.17-203.0.113.200"},
+		{"10.0.0.9-10.0.0.5", ""},
+		{"10.0.0", ""},
+		{"10.0.0.0/40", ""},
+	}
+	for _, tc := range testCases {
+		r, err := ParseRange(tc.input)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("ParseRange(%q) = %s; want error", tc.input, r)
+			}
+			continue
+		}
+		if err != nil || r.String() != tc.want {
+			t.Errorf("ParseRange(%q) = %s, %v; want %s", tc.input, r, err, tc.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	testCases := []struct {
+		name   string
+		inputs []string
+		want   string
+	}{
+		{"adjacent", []string{"10.0.0.0/25", "10.0.0.128/25", "10.0.1.0/24"}, "10.0.0.0/23"},
+		{"contained", []string{"10.0.0.0/16", "10.0.3.0/24", "10.0.0.1"}, "10.0.0.0/16"},
+		{"not aligned", []string{"10.0.1.0/24", "10.0.2.0/24"}, "10.0.1.0/24 10.0.2.0/24"},
+		{"unsorted", []string{"192.168.0.0/24", "10.0.0.0/8"}, "10.0.0.0/8 192.168.0.0/24"},
+		{"range", []string{"10.0.0.1-10.0.0.6"}, "10.0.0.1/32 10.0.0.2/31 10.0.0.4/31 10.0.0.6/32"},
+		{"overlapping ranges", []string{"10.0.0.0-10.0.0.200", "10.0.0.100-10.0.0.255"}, "10.0.0.0/24"},
+		{"everything", []string{"0.0.0.0/1", "128.0.0.0-255.255.255.255"}, "0.0.0.0/0"},
+		{"nothing", nil, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ranges []Range
+			for _, input := range tc.inputs {
+				r, err := ParseRange(input)
+				if err != nil {
+					t.Fatalf("ParseRange(%q) returned error: %v", input, err)
+				}
+				ranges = append(ranges, r)
+			}
+			var got []string
+			for _, n := range Summarize(ranges) {
+				got = append(got, n.CIDR())
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Errorf("Summarize(%v) = %v; want %s", tc.inputs, got, tc.want)
+			}
+		})
+	}
+}
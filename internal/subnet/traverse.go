@@ -0,0 +1,130 @@
+package subnet
+
+import "iter"
+
+// Order is the order in which a traversal visits the nodes of a tree.
+type Order int
+
+const (
+	// PreOrder visits parents before their children, in address order.
+	PreOrder Order = iota
+	// PostOrder visits children before their parents, in address order.
+	PostOrder
+	// BreadthFirst visits the nodes level by level, each level in address
+	// order.
+	BreadthFirst
+)
+
+// children returns the children of n, left first.
+func (n *Subnet) children() []*Subnet {
+	var children []*Subnet
+	if n.Left != nil {
+		children = append(children, n.Left)
+	}
+	if n.Right != nil {
+		children = append(children, n.Right)
+	}
+	return children
+}
+
+// WalkPreOrder calls f for n and every node below it, parents first, with
+// the node's depth below n. The walk stops when f returns false; WalkPreOrder
+// reports whether it finished.
+func (n *Subnet) WalkPreOrder(f func(n *Subnet, depth int) bool) bool {
+	return n.preOrder(f, 0)
+}
+
+func (n *Subnet) preOrder(f func(*Subnet, int) bool, depth int) bool {
+	if !f(n, depth) {
+		return false
+	}
+	for _, child := range n.children() {
+		if !child.preOrder(f, depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// WalkPostOrder is like WalkPreOrder but visits children before their parents.
+func (n *Subnet) WalkPostOrder(f func(n *Subnet, depth int) bool) bool {
+	return n.postOrder(f, 0)
+}
+
+func (n *Subnet) postOrder(f func(*Subnet, int) bool, depth int) bool {
+	for _, child := range n.children() {
+		if !child.postOrder(f, depth+1) {
+			return false
+		}
+	}
+	return f(n, depth)
+}
+
+// WalkBreadthFirst is like WalkPreOrder but visits the tree level by level.
+func (n *Subnet) WalkBreadthFirst(f func(n *Subnet, depth int) bool) bool {
+	level := []*Subnet{n}
+	for depth := 0; len(level) > 0; depth++ {
+		var next []*Subnet
+		for _, m := range level {
+			if !f(m, depth) {
+				return false
+			}
+			next = append(next, m.children()...)
+		}
+		level = next
+	}
+	return true
+}
+
+// walk walks the tree in the given order.
+func (n *Subnet) walk(order Order, f func(*Subnet, int) bool) bool {
+	switch order {
+	case PostOrder:
+		return n.WalkPostOrder(f)
+	case BreadthFirst:
+		return n.WalkBreadthFirst(f)
+	}
+	return n.WalkPreOrder(f)
+}
+
+// All returns an iterator over n and every node below it in the given order,
+// yielding each node with its depth below n.
+func (n *Subnet) All(order Order) iter.Seq2[*Subnet, int] {
+	return func(yield func(*Subnet, int) bool) {
+		n.walk(order, yield)
+	}
+}
+
+// All returns an iterator over the nodes of every root in turn, yielding
+// each with its depth below its root.
+func (ws *Workspace) All(order Order) iter.Seq2[*Subnet, int] {
+	return func(yield func(*Subnet, int) bool) {
+		for _, root := range ws.Roots {
+			if !root.walk(order, yield) {
+				return
+			}
+		}
+	}
+}
+
+// Nodes returns an iterator over the nodes of the plan in the given order.
+func Nodes(plan Plan, order Order) iter.Seq[*Subnet] {
+	return func(yield func(*Subnet) bool) {
+		for n := range plan.All(order) {
+			if !yield(n) {
+				return
+			}
+		}
+	}
+}
+
+// Leaves returns an iterator over the leaves of the plan in address order.
+func Leaves(plan Plan) iter.Seq[*Subnet] {
+	return func(yield func(*Subnet) bool) {
+		for n := range plan.All(PreOrder) {
+			if n.Left == nil && n.Right == nil && !yield(n) {
+				return
+			}
+		}
+	}
+}
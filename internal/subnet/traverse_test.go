@@ -0,0 +1,67 @@
+package subnet
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTraversal(t *testing.T) {
+	root, err := ReadFlat(strings.NewReader("10.0.0.0/25\n10.0.0.128/26\n10.0.0.192/26\n10.0.1.0/24\n"))
+	if err != nil {
+		t.Fatalf("ReadFlat returned error: %v", err)
+	}
+	tests := []struct {
+		order Order
+		want  string
+	}{
+		{PreOrder, "10.0.0.0/23@0 10.0.0.0/24@1 10.0.0.0/25@2 10.0.0.128/25@2 10.0.0.128/26@3 10.0.0.192/26@3 10.0.1.0/24@1"},
+		{PostOrder, "10.0.0.0/25@2 10.0.0.128/26@3 10.0.0.192/26@3 10.0.0.128/25@2 10.0.0.0/24@1 10.0.1.0/24@1 10.0.0.0/23@0"},
+		{BreadthFirst, "10.0.0.0/23@0 10.0.0.0/24@1 10.0.1.0/24@1 10.0.0.0/25@2 10.0.0.128/25@2 10.0.0.128/26@3 10.0.0.192/26@3"},
+	}
+	for _, test := range tests {
+		var got []string
+		for n, depth := range root.All(test.order) {
+			got = append(got, fmt.Sprintf("%s@%d", n.CIDR(), depth))
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("All(%d) = %s, want %s", test.order, strings.Join(got, " "), test.want)
+		}
+
+		// Stopping after three nodes visits the same first three.
+		got = got[:0]
+		finished := root.walk(test.order, func(n *Subnet, depth int) bool {
+			got = append(got, fmt.Sprintf("%s@%d", n.CIDR(), depth))
+			return len(got) < 3
+		})
+		want := strings.Join(strings.Fields(test.want)[:3], " ")
+		if finished || strings.Join(got, " ") != want {
+			t.Errorf("walk(%d) stopped early = %t %s, want false %s", test.order, finished, strings.Join(got, " "), want)
+		}
+	}
+
+	var leaves []string
+	for n := range Leaves(root) {
+		leaves = append(leaves, n.CIDR())
+		if n.MaskLen == 26 {
+			break
+		}
+	}
+	if want := "10.0.0.0/25 10.0.0.128/26"; strings.Join(leaves, " ") != want {
+		t.Errorf("Leaves with break = %s, want %s", strings.Join(leaves, " "), want)
+	}
+}
+
+func TestWorkspaceAll(t *testing.T) {
+	ws, err := ReadFlatWorkspace(strings.NewReader("root 10.0.0.0/24\n10.0.0.0/25\n10.0.0.128/25\nroot 192.168.0.0/24\n"))
+	if err != nil {
+		t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+	}
+	var got []string
+	for n := range Nodes(ws, PostOrder) {
+		got = append(got, n.CIDR())
+	}
+	if want := "10.0.0.0/25 10.0.0.128/25 10.0.0.0/24 192.168.0.0/24"; strings.Join(got, " ") != want {
+		t.Errorf("Nodes(PostOrder) = %s, want %s", strings.Join(got, " "), want)
+	}
+}
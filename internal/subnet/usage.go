@@ -0,0 +1,70 @@
+package subnet
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// UsedKey is the field holding the number of addresses in use in a leaf in
+// the flat plan format, as in "10.0.1.0/24 office used=180".
+const UsedKey = "used"
+
+// Size returns the number of addresses in n.
+func (n *Subnet) Size() uint64 {
+	return 1 << (32 - uint64(n.MaskLen))
+}
+
+// Used returns the number of addresses in use under n, summed over the leaves
+// that record it, and whether any leaf does.
+func (n *Subnet) Used() (uint64, bool) {
+	if n.Left == nil && n.Right == nil {
+		if n.InUse == nil {
+			return 0, false
+		}
+		return *n.InUse, true
+	}
+	var used uint64
+	var tracked bool
+	for _, child := range []*Subnet{n.Left, n.Right} {
+		if child != nil {
+			u, ok := child.Used()
+			used += u
+			tracked = tracked || ok
+		}
+	}
+	return used, tracked
+}
+
+// SetUsed records the number of addresses in use in the leaf n.
+func (n *Subnet) SetUsed(used uint64) error {
+	if n.Left != nil || n.Right != nil {
+		return fmt.Errorf("%s is divided; set usage on its leaves", n.CIDR())
+	}
+	if used > n.Size() {
+		return fmt.Errorf("%d addresses do not fit in %s", used, n.CIDR())
+	}
+	n.InUse = &used
+	return nil
+}
+
+// parseUsed removes the UsedKey field from metadata read from a flat plan
+// and returns its value, or nil if there is none.
+func parseUsed(metadata map[string]string) (*uint64, error) {
+	s, ok := metadata[UsedKey]
+	if !ok {
+		return nil, nil
+	}
+	used, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s=%q: want a number of addresses", UsedKey, s)
+	}
+	delete(metadata, UsedKey)
+	return &used, nil
+}
+
+// Utilization returns the fraction of n's addresses in use and whether any
+// leaf under n records its usage.
+func (n *Subnet) Utilization() (float64, bool) {
+	used, ok := n.Used()
+	return float64(used) / float64(n.Size()), ok
+}
@@ -0,0 +1,114 @@
+package subnet
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestUtilization(t *testing.T) {
+	root, err := ReadFlat(strings.NewReader("10.0.0.0/25 web used=64\n10.0.0.128/26 used=16\n10.0.0.192/26 db\n"))
+	if err != nil {
+		t.Fatalf("ReadFlat returned error: %v", err)
+	}
+	testCases := []struct {
+		cidr    string
+		used    uint64
+		tracked bool
+		util    float64
+	}{
+		{"10.0.0.0/24", 80, true, 0.3125},
+		{"10.0.0.0/25", 64, true, 0.5},
+		{"10.0.0.128/25", 16, true, 0.125},
+		{"10.0.0.192/26", 0, false, 0},
+	}
+	for _, tc := range testCases {
+		address, maskLen, _ := ParseCIDR(tc.cidr)
+		n := root.Find(address, maskLen)
+		used, tracked := n.Used()
+		util, _ := n.Utilization()
+		if used != tc.used || tracked != tc.tracked || util != tc.util {
+			t.Errorf("%s: Used() = %d, %t, Utilization() = %v; want %d, %t, %v", tc.cidr, used, tracked, util, tc.used, tc.tracked, tc.util)
+		}
+	}
+}
+
+func TestSetUsed(t *testing.T) {
+	root := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	if err := root.SetUsed(257); err == nil {
+		t.Errorf("SetUsed(257) on a /24 succeeded; want error")
+	}
+	if err := root.SetUsed(200); err != nil || root.InUse == nil || *root.InUse != 200 || root.Metadata != nil {
+		t.Errorf("SetUsed(200) = %v, metadata %v; want 200 in use and no metadata", err, root.Metadata)
+	}
+	root.Divide()
+	if err := root.SetUsed(10); err == nil {
+		t.Errorf("SetUsed on a divided subnet succeeded; want error")
+	}
+	if size := (&Subnet{}).Size(); size != 1<<32 {
+		t.Errorf("Size() of 0.0.0.0/0 = %d; want %d", size, uint64(1)<<32)
+	}
+}
+
+func TestUsedIsNotMetadata(t *testing.T) {
+	plan := "10.0.0.0/25 used=0\n10.0.0.128/26 web used=16\n10.0.0.192/26 used=5\n"
+	root, err := ReadFlat(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("ReadFlat returned error: %v", err)
+	}
+	free := []bool{true, false, false}
+	for i, leaf := range []*Subnet{root.Left, root.Right.Left, root.Right.Right} {
+		if leaf.Metadata != nil {
+			t.Errorf("%s metadata = %v; want none", leaf.CIDR(), leaf.Metadata)
+		}
+		if leaf.IsFree() != free[i] {
+			t.Errorf("%s IsFree() = %t; want %t", leaf.CIDR(), leaf.IsFree(), free[i])
+		}
+	}
+	if fields := root.Right.Left.Fields(); len(fields) != 1 {
+		t.Errorf("Fields() = %q; want only the label", fields)
+	}
+	var buf bytes.Buffer
+	WriteFlat(&buf, root)
+	if buf.String() != plan {
+		t.Errorf("round trip wrote\n%s\nwant\n%s", buf.String(), plan)
+	}
+	if _, err := ReadFlat(strings.NewReader("10.0.0.0/24 used=many\n")); err == nil {
+		t.Errorf("ReadFlat with used=many succeeded; want error")
+	}
+}
+
+func TestUsedKeyReserved(t *testing.T) {
+	if _, _, err := ParseFields("web used=3"); err == nil {
+		t.Errorf("ParseFields with used=3 succeeded; want error")
+	}
+	n := &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24, Metadata: map[string]string{UsedKey: "yes"}}
+	if err := WriteFlat(io.Discard, n); err == nil {
+		t.Errorf("WriteFlat with used metadata succeeded; want error")
+	}
+
+	// Usage saved as metadata by earlier versions loads as usage.
+	loaded, err := UnmarshalWorkspace([]byte(`{"Address": 167772160, "MaskLen": 24, "Metadata": {"used": "7", "vpc": "main"}}`), "plan.json")
+	if err != nil {
+		t.Fatalf("UnmarshalWorkspace returned error: %v", err)
+	}
+	if root := loaded.Roots[0]; root.InUse == nil || *root.InUse != 7 || len(root.Metadata) != 1 {
+		t.Errorf("legacy usage loaded as %v, metadata %v; want 7 in use and vpc only", root.InUse, root.Metadata)
+	}
+
+	// Recorded usage cannot be split between the halves of a division.
+	n = &Subnet{Address: InetAton("10.0.0.0"), MaskLen: 24}
+	n.SetUsed(10)
+	if err := n.CanDivide(); err == nil {
+		t.Errorf("CanDivide() with addresses in use succeeded; want error")
+	}
+	n.SetUsed(0)
+	if err := n.CanDivide(); err != nil {
+		t.Fatalf("CanDivide() with no addresses in use = %v; want nil", err)
+	}
+	n.Divide()
+	if n.InUse != nil || !n.Left.IsFree() {
+		t.Errorf("Divide kept usage %v on the divided subnet", n.InUse)
+	}
+}
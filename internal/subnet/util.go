@@ -1,8 +1,11 @@
 package subnet
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
+	"net/netip"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -37,6 +40,11 @@ func MaskLen(subnetMask uint32) uint32 {
 	return maskLen
 }
 
+// CommonMaskLen returns the length of the longest prefix shared by two addresses.
+func CommonMaskLen(a, b uint32) uint32 {
+	return MaskLen(^(a ^ b))
+}
+
 // networkAddress calculates the network address for a given IP address and subnet mask length.
 func NetworkAddress(ip, maskLen uint32) uint32 {
 	mask := SubnetNetmask(maskLen)
@@ -53,6 +61,24 @@ func SubnetLastAddress(subnet, maskLen uint32) uint32 {
 	return subnet + SubnetAddresses(maskLen) - 1
 }
 
+// UsableRange returns the first and last assignable host addresses in a subnet,
+// excluding the network and broadcast addresses except for /31 and /32.
+func UsableRange(subnet, maskLen uint32) (uint32, uint32) {
+	last := SubnetLastAddress(subnet, maskLen)
+	if maskLen >= 31 {
+		return subnet, last
+	}
+	return subnet + 1, last - 1
+}
+
+// UsableHosts returns the number of assignable host addresses in a subnet.
+func UsableHosts(maskLen uint32) uint32 {
+	if maskLen >= 31 {
+		return SubnetAddresses(maskLen)
+	}
+	return SubnetAddresses(maskLen) - 2
+}
+
 // IsValidIPAddress checks if the given string is a valid IPv4 address.
 func IsValidIPAddress(ip string) bool {
 	parts := strings.Split(ip, ".")
@@ -67,3 +93,47 @@ func IsValidIPAddress(ip string) bool {
 	}
 	return true
 }
+
+// ParseIP parses a dotted IPv4 address such as "10.0.0.1". Unlike
+// IsValidIPAddress it rejects octets with leading zeros or signs, which
+// InetAton cannot convert.
+func ParseIP(ip string) (uint32, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is4() {
+		return 0, fmt.Errorf("invalid IP address %q", ip)
+	}
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+// ParseCIDR parses a string such as "10.0.0.0/24" into an address and mask length.
+func ParseCIDR(cidr string) (uint32, uint32, error) {
+	addr, mask, ok := strings.Cut(cidr, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid CIDR %q: missing mask length", cidr)
+	}
+	address, err := ParseIP(addr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid CIDR %q: bad address", cidr)
+	}
+	maskLen, err := strconv.Atoi(mask)
+	if err != nil || strconv.Itoa(maskLen) != mask || maskLen < 0 || maskLen > 32 {
+		return 0, 0, fmt.Errorf("invalid CIDR %q: bad mask length", cidr)
+	}
+	return address, uint32(maskLen), nil
+}
+
+// FormatCIDR formats an address and mask length as "10.0.0.0/24".
+func FormatCIDR(address, maskLen uint32) string {
+	return fmt.Sprintf("%s/%d", InetNtoa(address), maskLen)
+}
+
+// SortedKeys returns the keys of a metadata map in sorted order.
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -27,3 +27,70 @@ func TestIsValidIPAddress(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCIDR(t *testing.T) {
+	testCases := []struct {
+		cidr    string
+		address string
+		maskLen uint32
+		wantErr bool
+	}{
+		{"10.0.0.0/8", "10.0.0.0", 8, false},
+		{"192.168.1.128/25", "192.168.1.128", 25, false},
+		{"0.0.0.0/0", "0.0.0.0", 0, false},
+		{"10.0.0.0", "", 0, true},
+		{"10.0.0.0/33", "", 0, true},
+		{"10.0.0/8", "", 0, true},
+		{"10.0.0.0/x", "", 0, true},
+		{"010.0.0.0/8", "", 0, true},
+		{"+10.0.0.0/8", "", 0, true},
+		{"10.0.0.0/+8", "", 0, true},
+		{"10.0.0.0/08", "", 0, true},
+		{"::ffff:10.0.0.0/104", "", 0, true},
+	}
+
+	for _, testCase := range testCases {
+		address, maskLen, err := ParseCIDR(testCase.cidr)
+		if testCase.wantErr {
+			if err == nil {
+				t.Errorf("ParseCIDR(%q) succeeded, expected an error", testCase.cidr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCIDR(%q) returned error %v", testCase.cidr, err)
+			continue
+		}
+		if InetNtoa(address) != testCase.address || maskLen != testCase.maskLen {
+			t.Errorf("ParseCIDR(%q) = %s/%d, expected %s/%d", testCase.cidr, InetNtoa(address), maskLen, testCase.address, testCase.maskLen)
+		}
+		if got := FormatCIDR(address, maskLen); got != testCase.cidr {
+			t.Errorf("FormatCIDR(%s, %d) = %q, expected %q", testCase.address, maskLen, got, testCase.cidr)
+		}
+	}
+}
+
+func TestUsableRange(t *testing.T) {
+	testCases := []struct {
+		cidr  string
+		first string
+		last  string
+		hosts uint32
+	}{
+		{"10.0.0.0/24", "10.0.0.1", "10.0.0.254", 254},
+		{"10.0.0.128/30", "10.0.0.129", "10.0.0.130", 2},
+		{"10.0.0.4/31", "10.0.0.4", "10.0.0.5", 2},
+		{"10.0.0.7/32", "10.0.0.7", "10.0.0.7", 1},
+	}
+
+	for _, testCase := range testCases {
+		address, maskLen, _ := ParseCIDR(testCase.cidr)
+		first, last := UsableRange(address, maskLen)
+		if InetNtoa(first) != testCase.first || InetNtoa(last) != testCase.last {
+			t.Errorf("UsableRange(%s) = %s - %s, expected %s - %s", testCase.cidr, InetNtoa(first), InetNtoa(last), testCase.first, testCase.last)
+		}
+		if hosts := UsableHosts(maskLen); hosts != testCase.hosts {
+			t.Errorf("UsableHosts(%d) = %d, expected %d", maskLen, hosts, testCase.hosts)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package subnet
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Plan is a tree or a workspace of trees. Exporters and importers accept
+// either.
+type Plan interface {
+	// Iterate calls f for every leaf in address order.
+	Iterate(f func(*Subnet))
+	// Walk calls f for every node in address order, parents first.
+	Walk(f func(*Subnet))
+	// All iterates over every node in the given order with its depth below
+	// its root.
+	All(order Order) iter.Seq2[*Subnet, int]
+	Find(address uint32, maskLen uint32) *Subnet
+	Locate(address uint32, maskLen uint32) *Subnet
+	DivideTo(address uint32, maskLen uint32) (*Subnet, error)
+}
+
+// Workspace is a set of disjoint root subnets planned together, such as
+// 10.0.0.0/8 and 172.16.0.0/12, kept sorted by address.
+type Workspace struct {
+	Roots []*Subnet `json:"roots"`
+}
+
+// NewWorkspace returns a workspace holding the given trees.
+func NewWorkspace(roots ...*Subnet) (*Workspace, error) {
+	ws := &Workspace{}
+	for _, root := range roots {
+		if err := ws.AddTree(root); err != nil {
+			return nil, err
+		}
+	}
+	return ws, nil
+}
+
+// Add adds an undivided root for the prefix containing address/maskLen.
+func (ws *Workspace) Add(address uint32, maskLen uint32) (*Subnet, error) {
+	if maskLen > 32 {
+		return nil, fmt.Errorf("invalid mask length %d", maskLen)
+	}
+	root := &Subnet{Address: NetworkAddress(address, maskLen), MaskLen: maskLen}
+	if err := ws.AddTree(root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// AddTree adds a tree as a new root. Host bits in the tree's addresses, as
+// written by older versions for roots like 10.0.0.5/24, are cleared. It fails
+// if the tree overlaps an existing root.
+func (ws *Workspace) AddTree(root *Subnet) error {
+	normalize(root, nil)
+	for _, other := range ws.Roots {
+		if Overlapping(root, other) {
+			return fmt.Errorf("%s overlaps %s", root.CIDR(), other.CIDR())
+		}
+	}
+	i, _ := slices.BinarySearchFunc(ws.Roots, root.Address, func(r *Subnet, address uint32) int {
+		return cmp.Compare(r.Address, address)
+	})
+	ws.Roots = slices.Insert(ws.Roots, i, root)
+	return nil
+}
+
+// normalize clears host bits in every address of the tree and sets the
+// parent links. Usage saved as metadata by earlier versions is moved to
+// InUse.
+func normalize(n, parent *Subnet) {
+	if n == nil {
+		return
+	}
+	n.Address = NetworkAddress(n.Address, n.MaskLen)
+	n.Parent = parent
+	if n.InUse == nil {
+		if used, err := parseUsed(n.Metadata); err == nil && used != nil {
+			n.InUse = used
+			if len(n.Metadata) == 0 {
+				n.Metadata = nil
+			}
+		}
+	}
+	normalize(n.Left, n)
+	normalize(n.Right, n)
+}
+
+// Remove deletes the root address/maskLen and everything planned inside it.
+func (ws *Workspace) Remove(address uint32, maskLen uint32) error {
+	for i, root := range ws.Roots {
+		if root.Address == address && root.MaskLen == maskLen {
+			ws.Roots = slices.Delete(ws.Roots, i, i+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a root", FormatCIDR(address, maskLen))
+}
+
+// Root returns the root containing the prefix address/maskLen, or nil.
+func (ws *Workspace) Root(address uint32, maskLen uint32) *Subnet {
+	for _, root := range ws.Roots {
+		if root.Contains(address, maskLen) {
+			return root
+		}
+	}
+	return nil
+}
+
+// Single returns the only root of the workspace, for callers that work on
+// one tree.
+func (ws *Workspace) Single() (*Subnet, error) {
+	if len(ws.Roots) == 0 {
+		return nil, fmt.Errorf("plan has no subnets")
+	}
+	if len(ws.Roots) != 1 {
+		cidrs := make([]string, len(ws.Roots))
+		for i, root := range ws.Roots {
+			cidrs[i] = root.CIDR()
+		}
+		return nil, fmt.Errorf("plan has %d roots, want 1: %s", len(ws.Roots), strings.Join(cidrs, " "))
+	}
+	return ws.Roots[0], nil
+}
+
+// Find returns the node for the prefix address/maskLen, or nil.
+func (ws *Workspace) Find(address uint32, maskLen uint32) *Subnet {
+	if root := ws.Root(address, maskLen); root != nil {
+		return root.Find(address, maskLen)
+	}
+	return nil
+}
+
+// Locate returns the most specific node containing the prefix, or nil if it
+// is outside every root.
+func (ws *Workspace) Locate(address uint32, maskLen uint32) *Subnet {
+	if root := ws.Root(address, maskLen); root != nil {
+		return root.Locate(address, maskLen)
+	}
+	return nil
+}
+
+// DivideTo divides the root containing the prefix down to it.
+func (ws *Workspace) DivideTo(address uint32, maskLen uint32) (*Subnet, error) {
+	root := ws.Root(address, maskLen)
+	if root == nil {
+		return nil, fmt.Errorf("%s is outside every root", FormatCIDR(address, maskLen))
+	}
+	return root.DivideTo(address, maskLen)
+}
+
+// Allocate allocates a prefix of maskLen from the root with the best fitting
+// free leaf, as Subnet.Allocate does within one tree.
+func (ws *Workspace) Allocate(maskLen uint32) (*Subnet, error) {
+	var best *Subnet
+	ws.Iterate(func(leaf *Subnet) {
+		if leaf.IsFree() && leaf.MaskLen <= maskLen && (best == nil || leaf.MaskLen > best.MaskLen) {
+			best = leaf
+		}
+	})
+	if best == nil {
+		return nil, fmt.Errorf("no free /%d left in the workspace", maskLen)
+	}
+	return best.Allocate(maskLen)
+}
+
+// Iterate calls f for every leaf of every root in address order.
+func (ws *Workspace) Iterate(f func(*Subnet)) {
+	for _, root := range ws.Roots {
+		root.Iterate(f)
+	}
+}
+
+// Walk calls f for every node of every root in address order, parents first.
+func (ws *Workspace) Walk(f func(*Subnet)) {
+	for _, root := range ws.Roots {
+		root.Walk(f)
+	}
+}
+
+// Clone returns a deep copy of the workspace.
+func (ws *Workspace) Clone() *Workspace {
+	c := &Workspace{Roots: make([]*Subnet, len(ws.Roots))}
+	for i, root := range ws.Roots {
+		c.Roots[i] = root.Clone()
+	}
+	return c
+}
+
+// WriteFlatWorkspace writes the workspace in the flat plan format. A single
+// root is written as by WriteFlat; with several, each root's subnets follow
+// a "root <cidr>" line.
+func WriteFlatWorkspace(w io.Writer, ws *Workspace) error {
+	if len(ws.Roots) == 1 {
+		return WriteFlat(w, ws.Roots[0])
+	}
+	for _, root := range ws.Roots {
+		if _, err := fmt.Fprintln(w, "root", root.CIDR()); err != nil {
+			return err
+		}
+		if err := WriteFlat(w, root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFlatWorkspace reads a workspace written by WriteFlatWorkspace, or a
+// single tree written by WriteFlat. A plan without subnets is an empty
+// workspace.
+func ReadFlatWorkspace(r io.Reader) (*Workspace, error) {
+	roots, err := parseFlat(r)
+	if err != nil {
+		return nil, err
+	}
+	ws := &Workspace{}
+	for _, f := range roots {
+		root, err := f.build()
+		if err != nil {
+			return nil, err
+		}
+		if err := ws.AddTree(root); err != nil {
+			return nil, fmt.Errorf("line %d: %w", f.line, err)
+		}
+	}
+	return ws, nil
+}
+
+// MarshalWorkspace encodes the workspace as a flat plan when filename ends
+// in ".plan" or ".txt", and as JSON otherwise. A workspace with one root is
+// encoded exactly like that tree, so single-root files stay readable by older
+// versions; none or several roots are encoded as a JSON object with a "roots"
+// list.
+func MarshalWorkspace(ws *Workspace, filename string) ([]byte, error) {
+	if isFlat(filename) {
+		var buf bytes.Buffer
+		err := WriteFlatWorkspace(&buf, ws)
+		return buf.Bytes(), err
+	}
+	if len(ws.Roots) == 1 {
+		return json.MarshalIndent(ws.Roots[0], "", "  ")
+	}
+	roots := ws.Roots
+	if roots == nil {
+		roots = []*Subnet{}
+	}
+	return json.MarshalIndent(Workspace{Roots: roots}, "", "  ")
+}
+
+// UnmarshalWorkspace decodes a workspace encoded by MarshalWorkspace for the
+// same filename, including single-tree JSON files written by older versions.
+func UnmarshalWorkspace(data []byte, filename string) (*Workspace, error) {
+	if isFlat(filename) {
+		return ReadFlatWorkspace(bytes.NewReader(data))
+	}
+	var file struct {
+		Roots json.RawMessage `json:"roots"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Roots == nil {
+		var root Subnet
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, err
+		}
+		return NewWorkspace(&root)
+	}
+	// Empty workspaces were once saved with "roots": null.
+	var roots []*Subnet
+	if err := json.Unmarshal(file.Roots, &roots); err != nil {
+		return nil, err
+	}
+	return NewWorkspace(roots...)
+}
+
+// SaveWorkspace saves the workspace in the format chosen by MarshalWorkspace.
+func SaveWorkspace(ws *Workspace, filename string) error {
+	data, err := MarshalWorkspace(ws, filename)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// LoadWorkspace loads a workspace saved with SaveWorkspace.
+func LoadWorkspace(filename string) (*Workspace, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalWorkspace(data, filename)
+}
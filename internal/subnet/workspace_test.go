@@ -0,0 +1,156 @@
+package subnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// roots returns the CIDRs of the workspace's roots.
+func roots(ws *Workspace) string {
+	var cidrs []string
+	for _, root := range ws.Roots {
+		cidrs = append(cidrs, root.CIDR())
+	}
+	return strings.Join(cidrs, " ")
+}
+
+func TestWorkspaceAdd(t *testing.T) {
+	ws := &Workspace{}
+	for _, cidr := range []string{"172.16.0.0/12", "10.0.0.0/8", "203.0.113.7/24"} {
+		address, maskLen, _ := ParseCIDR(cidr)
+		if _, err := ws.Add(address, maskLen); err != nil {
+			t.Fatalf("Add(%s) returned error: %v", cidr, err)
+		}
+	}
+	if got, want := roots(ws), "10.0.0.0/8 172.16.0.0/12 203.0.113.0/24"; got != want {
+		t.Errorf("roots = %s; want %s", got, want)
+	}
+
+	for _, cidr := range []string{"10.1.0.0/16", "172.0.0.0/8", "203.0.113.0/24"} {
+		address, maskLen, _ := ParseCIDR(cidr)
+		if _, err := ws.Add(address, maskLen); err == nil {
+			t.Errorf("Add(%s) succeeded; want overlap error", cidr)
+		}
+	}
+
+	if n, err := ws.DivideTo(InetAton("172.16.4.0"), 24); err != nil || n.CIDR() != "172.16.4.0/24" {
+		t.Errorf("DivideTo(172.16.4.0/24) = %v, %v", n, err)
+	}
+	if n := ws.Locate(InetAton("172.16.4.9"), 32); n == nil || n.CIDR() != "172.16.4.0/24" {
+		t.Errorf("Locate(172.16.4.9) = %v; want 172.16.4.0/24", n)
+	}
+	if n := ws.Locate(InetAton("192.168.0.1"), 32); n != nil {
+		t.Errorf("Locate(192.168.0.1) = %s; want nil", n.CIDR())
+	}
+
+	if err := ws.Remove(InetAton("172.16.0.0"), 12); err != nil {
+		t.Errorf("Remove(172.16.0.0/12) returned error: %v", err)
+	}
+	if err := ws.Remove(InetAton("10.0.0.0"), 16); err == nil {
+		t.Errorf("Remove(10.0.0.0/16) succeeded; want error for a non-root")
+	}
+	if got, want := roots(ws), "10.0.0.0/8 203.0.113.0/24"; got != want {
+		t.Errorf("roots after Remove = %s; want %s", got, want)
+	}
+}
+
+func TestWorkspaceFiles(t *testing.T) {
+	ws, err := NewWorkspace(
+		flatPlan(t, "192.168.0.0/25 lab\n192.168.0.128/25\n"),
+		flatPlan(t, "10.0.0.0/9 web\n10.128.0.0/9\n"),
+	)
+	if err != nil {
+		t.Fatalf("NewWorkspace returned error: %v", err)
+	}
+
+	for _, filename := range []string{"plan.json", "plan.plan"} {
+		data, err := MarshalWorkspace(ws, filename)
+		if err != nil {
+			t.Fatalf("MarshalWorkspace(%s) returned error: %v", filename, err)
+		}
+		loaded, err := UnmarshalWorkspace(data, filename)
+		if err != nil {
+			t.Fatalf("UnmarshalWorkspace(%s) returned error: %v", filename, err)
+		}
+		if len(DiffWorkspaces(ws, loaded)) != 0 || roots(loaded) != "10.0.0.0/8 192.168.0.0/24" {
+			t.Errorf("%s round trip = %s with changes %v", filename, roots(loaded), DiffWorkspaces(ws, loaded))
+		}
+		if _, err := loaded.Single(); err == nil {
+			t.Errorf("Single() of two roots from %s succeeded; want error", filename)
+		}
+	}
+
+	// Single-tree files written by older versions, including roots with
+	// host bits set, load as one root.
+	legacy := &Subnet{Address: InetAton("10.0.0.5"), MaskLen: 24}
+	legacy.Divide()
+	data, _ := json.Marshal(legacy)
+	loaded, err := UnmarshalWorkspace(data, "plan.json")
+	if err != nil {
+		t.Fatalf("UnmarshalWorkspace(legacy) returned error: %v", err)
+	}
+	if roots(loaded) != "10.0.0.0/24" || loaded.Roots[0].Right.CIDR() != "10.0.0.128/25" || loaded.Roots[0].Right.Parent != loaded.Roots[0] {
+		t.Errorf("legacy tree loaded as %s", roots(loaded))
+	}
+	single, _ := MarshalWorkspace(loaded, "plan.plan")
+	if string(single) != "10.0.0.0/25\n10.0.0.128/25\n" {
+		t.Errorf("single root flat plan = %q; want no root lines", single)
+	}
+
+	for _, input := range []string{
+		"10.0.0.0/24\nroot 10.1.0.0/16\n",
+		"root 10.0.0.0/16\nroot 10.0.0.0/8\n",
+		"root 10.0.0.0/16\n10.1.0.0/24\n",
+		"root\n",
+	} {
+		if _, err := ReadFlatWorkspace(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadFlatWorkspace(%q) succeeded; want error", input)
+		}
+	}
+	var buf bytes.Buffer
+	WriteFlatWorkspace(&buf, ws)
+	if !strings.HasPrefix(buf.String(), "root 10.0.0.0/8\n10.0.0.0/9 web\n") {
+		t.Errorf("WriteFlatWorkspace wrote\n%s", buf.String())
+	}
+}
+
+func TestEmptyWorkspace(t *testing.T) {
+	for _, filename := range []string{"plan.json", "plan.plan"} {
+		data, err := MarshalWorkspace(&Workspace{}, filename)
+		if err != nil {
+			t.Fatalf("MarshalWorkspace(%s) returned error: %v", filename, err)
+		}
+		loaded, err := UnmarshalWorkspace(data, filename)
+		if err != nil || len(loaded.Roots) != 0 {
+			t.Errorf("UnmarshalWorkspace(%q, %s) = %v, %v; want no roots", data, filename, loaded, err)
+		}
+	}
+	// Earlier versions saved an empty workspace with null roots.
+	loaded, err := UnmarshalWorkspace([]byte(`{"roots": null}`), "plan.json")
+	if err != nil || len(loaded.Roots) != 0 {
+		t.Errorf("UnmarshalWorkspace(null roots) = %v, %v; want no roots", loaded, err)
+	}
+	if _, err := loaded.Single(); err == nil {
+		t.Errorf("Single() of an empty workspace succeeded; want error")
+	}
+}
+
+func TestSaveTree(t *testing.T) {
+	root := flatPlan(t, "10.0.0.0/25 web\n10.0.0.128/25\n")
+	for _, filename := range []string{"plan.json", "plan.plan"} {
+		path := filepath.Join(t.TempDir(), filename)
+		if err := SaveTree(root, path); err != nil {
+			t.Fatalf("SaveTree(%s) returned error: %v", filename, err)
+		}
+		loaded, err := LoadTree(path)
+		if err != nil {
+			t.Fatalf("LoadTree(%s) returned error: %v", filename, err)
+		}
+		if len(Diff(root, loaded)) != 0 || loaded.Left.Parent != loaded {
+			t.Errorf("%s round trip changed %v", filename, Diff(root, loaded))
+		}
+	}
+}
@@ -15,12 +15,23 @@ const (
 	white  = lipgloss.Color("#ffffff")
 	black  = lipgloss.Color("#000000")
 	grey = lipgloss.Color("#7c7980")
+	green  = lipgloss.Color("#04b575")
+	yellow = lipgloss.Color("#e5c07b")
+	red    = lipgloss.Color("#e06c75")
 )
 
 type Styles struct {
 	Shapes     lipgloss.Style
 	Selected   lipgloss.Style
 	Unselected lipgloss.Style
+
+	// Low, Medium and High colour unselected rows by their node's
+	// utilization: below MediumAt, below HighAt, and from HighAt up.
+	Low      lipgloss.Style
+	Medium   lipgloss.Style
+	High     lipgloss.Style
+	MediumAt float64
+	HighAt   float64
 }
 
 func defaultStyles() Styles {
@@ -28,13 +39,34 @@ func defaultStyles() Styles {
 		Shapes:     lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(grey),
 		Selected:   lipgloss.NewStyle().Margin(0, 0, 0, 0).Background(grey),
 		Unselected: lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(lipgloss.AdaptiveColor{Light: "#000000", Dark: "#ffffff"}),
+		Low:        lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(green),
+		Medium:     lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(yellow),
+		High:       lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(red),
+		MediumAt:   0.7,
+		HighAt:     0.9,
+	}
+}
+
+// rowStyle returns the style of an unselected row.
+func (s Styles) rowStyle(node Node) lipgloss.Style {
+	switch {
+	case node.Utilization < 0:
+		return s.Unselected
+	case node.Utilization >= s.HighAt:
+		return s.High
+	case node.Utilization >= s.MediumAt:
+		return s.Medium
 	}
+	return s.Low
 }
 
 type Node struct {
 	Value    string
 	Desc     string
 	Children []Node
+	// Utilization is the fraction of the node in use, or negative if it is
+	// not tracked.
+	Utilization float64
 }
 
 type Model struct {
@@ -218,7 +250,8 @@ func (m *Model) renderTree(remainingNodes []Node, indent int, count *int) string
 		if m.cursor == idx {
 			str += fmt.Sprintf("%s\t\t%s\n", m.Styles.Selected.Render(valueStr), m.Styles.Selected.Render(descStr))
 		} else {
-			str += fmt.Sprintf("%s\t\t%s\n", m.Styles.Unselected.Render(valueStr), m.Styles.Unselected.Render(descStr))
+			style := m.Styles.rowStyle(node)
+			str += fmt.Sprintf("%s\t\t%s\n", style.Render(valueStr), style.Render(descStr))
 		}
 
 		b.WriteString(str)
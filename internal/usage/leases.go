@@ -0,0 +1,122 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// ParseDhcpdLeases reads an ISC dhcpd.leases file. The file is a log, so the
+// last lease of each address wins; only leases in the active binding state
+// are returned, in the order their addresses first appear.
+//
+//	lease 10.0.0.20 {
+//	  binding state active;
+//	  hardware ethernet 52:54:00:12:34:56;
+//	  client-hostname "laptop";
+//	}
+func ParseDhcpdLeases(r io.Reader) ([]Observation, error) {
+	var order []string
+	leases := make(map[string]Observation)
+	active := make(map[string]bool)
+
+	var current *Observation
+	var state string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(strings.TrimSuffix(text, ";"))
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "#"):
+		case fields[0] == "lease" && len(fields) == 3 && fields[2] == "{":
+			if !isIP(fields[1]) {
+				return nil, fmt.Errorf("line %d: invalid address %q", line, fields[1])
+			}
+			current, state = &Observation{Address: fields[1]}, "active"
+		case current == nil:
+		case fields[0] == "}":
+			if _, ok := leases[current.Address]; !ok {
+				order = append(order, current.Address)
+			}
+			leases[current.Address] = *current
+			active[current.Address] = state == "active"
+			current = nil
+		case len(fields) == 3 && fields[0] == "binding" && fields[1] == "state":
+			state = fields[2]
+		case len(fields) == 3 && fields[0] == "hardware" && fields[1] == "ethernet":
+			current.Host.MAC = fields[2]
+		case len(fields) == 2 && fields[0] == "client-hostname":
+			current.Host.Hostname = strings.Trim(fields[1], `"`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var observations []Observation
+	for _, address := range order {
+		if active[address] {
+			observations = append(observations, leases[address])
+		}
+	}
+	return observations, nil
+}
+
+// ParseKeaLeases reads a Kea memfile lease CSV (kea-leases4.csv). Like
+// dhcpd.leases it is appended to, so the last row of each address wins;
+// declined and expired-reclaimed leases are skipped.
+func ParseKeaLeases(r io.Reader) ([]Observation, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["address"]; !ok {
+		return nil, fmt.Errorf("lease file has no address column")
+	}
+	cell := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var order []string
+	leases := make(map[string]Observation)
+	active := make(map[string]bool)
+	for i, record := range records[1:] {
+		address := cell(record, "address")
+		if !isIP(address) {
+			return nil, fmt.Errorf("row %d: invalid address %q", i+2, address)
+		}
+		if _, ok := leases[address]; !ok {
+			order = append(order, address)
+		}
+		leases[address] = Observation{address, subnet.Host{
+			Hostname: strings.TrimSuffix(cell(record, "hostname"), "."),
+			MAC:      cell(record, "hwaddr"),
+		}}
+		state := cell(record, "state")
+		active[address] = state == "" || state == "0"
+	}
+
+	var observations []Observation
+	for _, address := range order {
+		if active[address] {
+			observations = append(observations, leases[address])
+		}
+	}
+	return observations, nil
+}
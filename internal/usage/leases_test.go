@@ -0,0 +1,60 @@
+package usage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDhcpdLeases(t *testing.T) {
+	input := `# The format of this file is documented in the dhcpd.leases(5) manual page.
+lease 10.0.0.20 {
+  starts 4 2024/01/04 10:00:00;
+  binding state active;
+  next binding state free;
+  hardware ethernet 52:54:00:00:00:20;
+  client-hostname "laptop";
+}
+lease 10.0.0.21 {
+  binding state free;
+  hardware ethernet 52:54:00:00:00:21;
+}
+lease 10.0.0.22 {
+  binding state active;
+  hardware ethernet 52:54:00:00:00:22;
+}
+lease 10.0.0.20 {
+  binding state active;
+  hardware ethernet 52:54:00:00:00:99;
+  client-hostname "laptop";
+}
+lease 10.0.0.22 {
+  binding state expired;
+}
+`
+	got, err := ParseDhcpdLeases(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDhcpdLeases returned error: %v", err)
+	}
+	if want := "10.0.0.20 laptop 52:54:00:00:00:99"; describe(got) != want {
+		t.Errorf("ParseDhcpdLeases =\n%s\nwant\n%s", describe(got), want)
+	}
+}
+
+func TestParseKeaLeases(t *testing.T) {
+	input := `address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state,user_context
+10.0.0.30,52:54:00:00:00:30,,3600,1704366000,1,0,0,printer.example.com.,0,
+10.0.0.31,52:54:00:00:00:31,,3600,1704366000,1,0,0,,1,
+10.0.0.32,52:54:00:00:00:32,,3600,1704366000,1,0,0,,0,
+10.0.0.32,52:54:00:00:00:32,,0,1704366000,1,0,0,,2,
+`
+	got, err := ParseKeaLeases(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseKeaLeases returned error: %v", err)
+	}
+	if want := "10.0.0.30 printer.example.com 52:54:00:00:00:30"; describe(got) != want {
+		t.Errorf("ParseKeaLeases =\n%s\nwant\n%s", describe(got), want)
+	}
+	if _, err := ParseKeaLeases(strings.NewReader("ip,mac\n10.0.0.1,x\n")); err == nil {
+		t.Errorf("ParseKeaLeases without an address column succeeded; want error")
+	}
+}
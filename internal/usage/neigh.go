@@ -0,0 +1,59 @@
+package usage
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// ParseNeigh reads the output of "ip neigh" (or "ip -4 neigh show"). Entries
+// without a link-layer address, such as FAILED and INCOMPLETE ones, and IPv6
+// entries are skipped.
+//
+//	10.0.0.1 dev eth0 lladdr 52:54:00:12:34:56 REACHABLE
+func ParseNeigh(r io.Reader) ([]Observation, error) {
+	var observations []Observation
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !isIP(fields[0]) {
+			continue
+		}
+		for i := 1; i+1 < len(fields); i++ {
+			if fields[i] == "lladdr" {
+				observations = append(observations, Observation{fields[0], subnet.Host{MAC: fields[i+1]}})
+				break
+			}
+		}
+	}
+	return observations, scanner.Err()
+}
+
+// ParseARP reads the output of "arp -an" or "arp -a". Incomplete entries are
+// skipped; names other than "?" are kept as hostnames.
+//
+//	? (10.0.0.1) at 52:54:00:12:34:56 [ether] on eth0
+//	gw.example.com (10.0.0.1) at 52:54:00:12:34:56 on en0 ifscope [ethernet]
+func ParseARP(r io.Reader) ([]Observation, error) {
+	var observations []Observation
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] != "at" {
+			continue
+		}
+		address := strings.Trim(fields[1], "()")
+		mac := fields[3]
+		if !isIP(address) || !strings.Contains(mac, ":") {
+			continue
+		}
+		h := subnet.Host{MAC: mac}
+		if fields[0] != "?" {
+			h.Hostname = fields[0]
+		}
+		observations = append(observations, Observation{address, h})
+	}
+	return observations, scanner.Err()
+}
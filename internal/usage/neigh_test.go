@@ -0,0 +1,50 @@
+package usage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describe formats observations for comparison in tests.
+func describe(observations []Observation) string {
+	var lines []string
+	for _, o := range observations {
+		lines = append(lines, fmt.Sprintf("%s %s %s", o.Address, o.Host.Hostname, o.Host.MAC))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParseNeigh(t *testing.T) {
+	input := `10.0.0.1 dev eth0 lladdr 52:54:00:00:00:01 REACHABLE
+10.0.0.7 dev eth0 lladdr 52:54:00:00:00:07 router STALE
+10.0.0.9 dev eth0  FAILED
+10.0.0.10 dev eth0  INCOMPLETE
+fe80::1 dev eth0 lladdr 52:54:00:00:00:01 router REACHABLE
+`
+	got, err := ParseNeigh(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseNeigh returned error: %v", err)
+	}
+	want := "10.0.0.1  52:54:00:00:00:01\n10.0.0.7  52:54:00:00:00:07"
+	if describe(got) != want {
+		t.Errorf("ParseNeigh =\n%s\nwant\n%s", describe(got), want)
+	}
+}
+
+func TestParseARP(t *testing.T) {
+	input := `? (10.0.0.1) at 52:54:00:00:00:01 [ether] on eth0
+gw.example.com (10.0.0.254) at 52:54:00:00:00:fe on en0 ifscope [ethernet]
+? (10.0.0.9) at <incomplete> on eth0
+? (10.0.0.10) at (incomplete) on en0 ifscope [ethernet]
+Address HWtype HWaddress Flags Mask Iface
+`
+	got, err := ParseARP(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseARP returned error: %v", err)
+	}
+	want := "10.0.0.1  52:54:00:00:00:01\n10.0.0.254 gw.example.com 52:54:00:00:00:fe"
+	if describe(got) != want {
+		t.Errorf("ParseARP =\n%s\nwant\n%s", describe(got), want)
+	}
+}
@@ -0,0 +1,72 @@
+// Package usage reads the addresses actually in use on a network from saved
+// neighbour tables and DHCP leases and records them in a plan.
+package usage
+
+import (
+	"fmt"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// Observation is an address seen in use and what is known about its host.
+type Observation struct {
+	Address string
+	Host    subnet.Host
+}
+
+// Skipped is an observation Apply could not record.
+type Skipped struct {
+	Observation
+	Err error
+}
+
+func (s Skipped) Error() string {
+	return fmt.Sprintf("%s: %v", s.Address, s.Err)
+}
+
+// Apply records each observed address as a host of the most specific subnet
+// of the plan containing it, keeping known host details the observation
+// lacks, and sets the used count of every leaf it touched to its number of
+// hosts. Observations outside the plan or on a network or broadcast address
+// of its leaf are skipped and returned.
+func Apply(plan subnet.Plan, observations []Observation) ([]Skipped, error) {
+	var skipped []Skipped
+	touched := make(map[*subnet.Subnet]bool)
+	var leaves []*subnet.Subnet
+	for _, o := range observations {
+		n := plan.Locate(subnet.InetAton(o.Address), 32)
+		if n == nil {
+			skipped = append(skipped, Skipped{o, fmt.Errorf("outside the plan")})
+			continue
+		}
+		h := o.Host
+		if known, ok := n.Hosts[o.Address]; ok {
+			if h.Hostname == "" {
+				h.Hostname = known.Hostname
+			}
+			if h.MAC == "" {
+				h.MAC = known.MAC
+			}
+		}
+		if err := n.SetHost(o.Address, h); err != nil {
+			skipped = append(skipped, Skipped{o, err})
+			continue
+		}
+		if !touched[n] {
+			touched[n] = true
+			leaves = append(leaves, n)
+		}
+	}
+	for _, n := range leaves {
+		if err := n.SetUsed(uint64(len(n.Hosts))); err != nil {
+			return nil, err
+		}
+	}
+	return skipped, nil
+}
+
+// isIP reports whether s is a dotted IPv4 address.
+func isIP(s string) bool {
+	_, err := subnet.ParseIP(s)
+	return err == nil
+}
@@ -0,0 +1,42 @@
+package usage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+func TestApply(t *testing.T) {
+	ws, err := subnet.ReadFlatWorkspace(strings.NewReader("10.0.0.0/25 office\n  host 10.0.0.5 hostname=printer\n10.0.0.128/25\n"))
+	if err != nil {
+		t.Fatalf("ReadFlatWorkspace returned error: %v", err)
+	}
+	skipped, err := Apply(ws, []Observation{
+		{"10.0.0.5", subnet.Host{MAC: "52:54:00:00:00:05"}},
+		{"10.0.0.6", subnet.Host{MAC: "52:54:00:00:00:06"}},
+		{"10.0.0.6", subnet.Host{MAC: "52:54:00:00:00:06"}},
+		{"10.0.0.200", subnet.Host{}},
+		{"10.0.0.127", subnet.Host{}},
+		{"192.168.1.1", subnet.Host{}},
+	})
+	if err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if len(skipped) != 2 || skipped[0].Address != "10.0.0.127" || skipped[1].Address != "192.168.1.1" {
+		t.Errorf("Apply skipped = %v; want the broadcast address 10.0.0.127 and 192.168.1.1", skipped)
+	}
+	office := ws.Find(subnet.InetAton("10.0.0.0"), 25)
+	if h := office.Hosts["10.0.0.5"]; h.Hostname != "printer" || h.MAC != "52:54:00:00:00:05" {
+		t.Errorf("10.0.0.5 = %+v; want the known hostname and the observed MAC", h)
+	}
+	for _, tc := range []struct {
+		cidr string
+		used uint64
+	}{{"10.0.0.0/25", 2}, {"10.0.0.128/25", 1}, {"10.0.0.0/24", 3}} {
+		address, maskLen, _ := subnet.ParseCIDR(tc.cidr)
+		if used, _ := ws.Find(address, maskLen).Used(); used != tc.used {
+			t.Errorf("%s used = %d; want %d", tc.cidr, used, tc.used)
+		}
+	}
+}
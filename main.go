@@ -1,29 +1,46 @@
 package main
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
-	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/rochana-atapattu/subnets/internal/store"
 	"github.com/rochana-atapattu/subnets/internal/subnet"
 	"github.com/rochana-atapattu/subnets/internal/tree"
 	"golang.org/x/term"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 )
 
+// planFile is the plan the TUI edits.
+const planFile = "subnets.json"
+
 var (
 	styleDoc  = lipgloss.NewStyle().Padding(1)
 	styleHelp = lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(lipgloss.AdaptiveColor{Light: "#000000", Dark: "#ffffff"})
 )
 
 type model struct {
-	subnet *subnet.Subnet
-	tree   tree.Model
+	workspace *subnet.Workspace
+	store     *store.Store
+	tree      tree.Model
+
+	// input is the prompt shown while submit is set, which receives the
+	// entered text.
+	input  textinput.Model
+	submit func(m *model, value string)
+	// removing is the root a first remove key press asked to confirm.
+	removing string
+	// compacting is set after a first compact key press listed the joins.
+	compacting bool
 
 	width  int
 	height int
@@ -31,15 +48,47 @@ type model struct {
 	Help     help.Model
 	KeyMap   KeyMap
 	showHelp bool
+	// showFree shows the free space panel next to the tree.
+	showFree bool
+	// hostsOf is the leaf whose host assignments are shown instead of the
+	// tree, if any.
+	hostsOf string
+	// byUtilization lists the subnets with tracked usage, fullest first,
+	// instead of the tree.
+	byUtilization bool
+	// filter lists only the leaves matching a query instead of the tree.
+	filter *subnet.Query
+
+	// status is a one line message shown above the help.
+	status string
+	// fileChanged is set while the prompt to reload or merge a plan file
+	// changed by another process is shown. ignored is the hash of the file
+	// contents whose prompt was ignored, so it is not shown again until the
+	// file changes once more or the model catches up.
+	fileChanged bool
+	ignored     *[sha256.Size]byte
 }
 
 // KeyMap holds the key bindings for the table.
 type KeyMap struct {
-	Divide key.Binding
-	Join   key.Binding
-	Save   key.Binding
-	Load   key.Binding
-	Quit   key.Binding
+	Divide     key.Binding
+	Join       key.Binding
+	Compact    key.Binding
+	AddRoot    key.Binding
+	RemoveRoot key.Binding
+	Save       key.Binding
+	Export     key.Binding
+	Free       key.Binding
+	SetUsed    key.Binding
+	SortUsage  key.Binding
+	Hosts      key.Binding
+	AssignHost key.Binding
+	Filter     key.Binding
+	Quit       key.Binding
+
+	Reload key.Binding
+	Merge  key.Binding
+	Ignore key.Binding
 
 	ShowFullHelp  key.Binding
 	CloseFullHelp key.Binding
@@ -56,13 +105,49 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("j"),
 			key.WithHelp("j", "join"),
 		),
+		Compact: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "compact free space"),
+		),
+		AddRoot: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "add root"),
+		),
+		RemoveRoot: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "remove root"),
+		),
 		Save: key.NewBinding(
 			key.WithKeys("s"),
 			key.WithHelp("s", "save"),
 		),
-		Load: key.NewBinding(
-			key.WithKeys("l"),
-			key.WithHelp("l", "load"),
+		Export: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "export terraform"),
+		),
+		Free: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "free space"),
+		),
+		SetUsed: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "set usage"),
+		),
+		SortUsage: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", "sort by utilization"),
+		),
+		Hosts: key.NewBinding(
+			key.WithKeys("h"),
+			key.WithHelp("h", "hosts"),
+		),
+		AssignHost: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "assign next free address"),
+		),
+		Filter: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "filter"),
 		),
 		ShowFullHelp: key.NewBinding(
 			key.WithKeys("?"),
@@ -77,11 +162,24 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("q", "esc"),
 			key.WithHelp("q", "quit"),
 		),
+
+		Reload: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "reload"),
+		),
+		Merge: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "merge"),
+		),
+		Ignore: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "ignore"),
+		),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return watchFile()
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -91,53 +189,81 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	)
 
 	switch msg := msg.(type) {
+	case checkFileMsg:
+		return m, tea.Batch(checkFile(m.store), watchFile())
+	case fileChangedMsg:
+		m.notifyChanged(msg.sum)
+		return m, nil
 	case tea.KeyMsg:
+		if m.submit != nil {
+			cmd = m.updatePrompt(msg)
+			m.rows()
+			return m, cmd
+		}
+		// The hosts view takes every key but quitting, saving, help and the
+		// file changed prompt; esc closes it.
+		if m.hostsOf != "" && (msg.Type == tea.KeyEsc || !key.Matches(msg, m.KeyMap.Quit, m.KeyMap.Save, m.KeyMap.Reload, m.KeyMap.Merge, m.KeyMap.Ignore, m.KeyMap.ShowFullHelp)) {
+			cmd = m.updateHosts(msg)
+			m.rows()
+			return m, cmd
+		}
+		if !key.Matches(msg, m.KeyMap.RemoveRoot) {
+			m.removing = ""
+		}
+		if !key.Matches(msg, m.KeyMap.Compact) {
+			m.compacting = false
+		}
 		switch {
 		case key.Matches(msg, m.KeyMap.Quit):
 			return m, tea.Quit
+		case m.fileChanged && key.Matches(msg, m.KeyMap.Reload):
+			m.reloadPlan()
+		case m.fileChanged && key.Matches(msg, m.KeyMap.Merge):
+			m.mergePlan()
+		case m.fileChanged && key.Matches(msg, m.KeyMap.Ignore):
+			m.ignoreChanged()
 		case key.Matches(msg, m.KeyMap.Divide):
-			node, ok := m.tree.GetNodeAtCurrentCursor()
-			if !ok {
+			n := m.selected()
+			if n == nil {
 				fmt.Println("No node found")
 				return m, nil
 			}
-			// fmt.Println("Node found", node.Value)
-			s := strings.Split(node.Value, "/")
-			addr := subnet.InetAton(s[0])
-			// maskInt is s[1] in uint32
-			maskInt, err := strconv.Atoi(s[1])
-			if err != nil {
-				fmt.Println("Error converting mask to int:", err)
-				return m, nil
+			if err := n.CanDivide(); err != nil {
+				m.status = err.Error()
+				break
 			}
-			maskLen := uint32(maskInt)
-			m.subnet.Find(addr, maskLen).Divide()
+			n.Divide()
 		case key.Matches(msg, m.KeyMap.Join):
-			node, ok := m.tree.GetNodeAtCurrentCursor()
-			if !ok {
+			n := m.selected()
+			if n == nil {
 				fmt.Println("No node found")
 				return m, nil
 			}
-			// fmt.Println("Node found", node.Value)
-			s := strings.Split(node.Value, "/")
-			addr := subnet.InetAton(s[0])
-			// maskInt is s[1] in uint32
-			maskInt, err := strconv.Atoi(s[1])
-			if err != nil {
-				fmt.Println("Error converting mask to int:", err)
-				return m, nil
+			if err := n.CanJoin(); err != nil {
+				m.status = err.Error()
+				break
 			}
-			maskLen := uint32(maskInt)
-			m.subnet.Find(addr, maskLen).Join()
+			n.Join()
+		case key.Matches(msg, m.KeyMap.Compact):
+			m.compact()
+		case key.Matches(msg, m.KeyMap.AddRoot):
+			return m, m.startAddRoot()
+		case key.Matches(msg, m.KeyMap.RemoveRoot):
+			m.removeRoot()
 		case key.Matches(msg, m.KeyMap.Save):
-			subnet.SaveTree(m.subnet, "subnets.json")
-		case key.Matches(msg, m.KeyMap.Load):
-			subnet, err := subnet.LoadTree("subnets.json")
-			if err != nil {
-				fmt.Println("Error loading subnet tree:", err)
-				return m, nil
-			}
-			m.subnet = subnet
+			m.savePlan()
+		case key.Matches(msg, m.KeyMap.Export):
+			m.exportTerraform()
+		case key.Matches(msg, m.KeyMap.Free):
+			m.showFree = !m.showFree
+		case key.Matches(msg, m.KeyMap.SetUsed):
+			return m, m.startSetUsed()
+		case key.Matches(msg, m.KeyMap.SortUsage):
+			m.byUtilization = !m.byUtilization
+		case key.Matches(msg, m.KeyMap.Hosts):
+			m.showHosts()
+		case key.Matches(msg, m.KeyMap.Filter):
+			return m, m.startFilter()
 		case key.Matches(msg, m.KeyMap.ShowFullHelp):
 			fallthrough
 		case key.Matches(msg, m.KeyMap.CloseFullHelp):
@@ -161,13 +287,43 @@ func (m model) View() string {
 		help = m.helpView()
 		availableHeight -= lipgloss.Height(help)
 	}
-	sections = append(sections, lipgloss.NewStyle().Height(availableHeight).Render(m.tree.View(), help))
+	if m.submit != nil {
+		input := m.input.View()
+		help = lipgloss.JoinVertical(lipgloss.Left, input, help)
+		availableHeight -= lipgloss.Height(input)
+	}
+	if m.status != "" {
+		status := styleStatus.Width(m.width).Render(m.status)
+		help = lipgloss.JoinVertical(lipgloss.Left, status, help)
+		availableHeight -= lipgloss.Height(status)
+	}
+	view := m.tree.View()
+	if m.hostsOf != "" {
+		view = m.hostsView()
+	}
+	if m.showFree {
+		view = lipgloss.JoinHorizontal(lipgloss.Top, view, " ", m.freeView())
+	}
+	sections = append(sections, lipgloss.NewStyle().Height(availableHeight).Render(view, help))
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
 func (m *model) rows() {
 
-	m.tree.SetNodes([]tree.Node{toNodeTree(m.subnet)})
+	nodes := make([]tree.Node, len(m.workspace.Roots))
+	for i, root := range m.workspace.Roots {
+		nodes[i] = toNodeTree(root)
+	}
+	if m.filter != nil {
+		nodes = m.filterRows()
+	}
+	if m.byUtilization {
+		nodes = m.utilizationRows()
+	}
+	m.tree.SetNodes(nodes)
+	if count := m.tree.NumberOfNodes(); m.tree.Cursor() >= count {
+		m.tree.SetCursor(max(count-1, 0))
+	}
 
 }
 
@@ -175,26 +331,52 @@ func (m *model) SetShowHelp() bool {
 	return m.showHelp
 }
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Println("Usage: subnets <IP address> <mask length>")
-		os.Exit(1)
+	if len(os.Args) > 1 {
+		if c, ok := commands[os.Args[1]]; ok {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Println("Error:", err)
+				if errors.Is(err, errUsage) {
+					fmt.Println("Usage:", c.usage)
+				}
+				os.Exit(1)
+			}
+			return
+		}
 	}
-	ipAddr := os.Args[1]
-	maskLengthStr := os.Args[2]
+	var root *subnet.Subnet
+	if len(os.Args) >= 3 {
+		ipAddr := os.Args[1]
+		maskLengthStr := os.Args[2]
 
-	// Validate the provided IP address
-	if !subnet.IsValidIPAddress(ipAddr) {
-		fmt.Println("Invalid IP address:", ipAddr)
-		os.Exit(1)
-	}
+		// Validate the provided IP address
+		address, err := subnet.ParseIP(ipAddr)
+		if err != nil {
+			fmt.Println("Invalid IP address:", ipAddr)
+			os.Exit(1)
+		}
 
-	// Convert the mask length from string to integer
-	maskLength, err := strconv.Atoi(maskLengthStr)
-	if err != nil || maskLength < 0 || maskLength > 32 {
-		fmt.Println("Invalid mask length:", maskLengthStr)
+		// Convert the mask length from string to integer
+		maskLength, err := strconv.Atoi(maskLengthStr)
+		if err != nil || maskLength < 0 || maskLength > 32 {
+			fmt.Println("Invalid mask length:", maskLengthStr)
+			os.Exit(1)
+		}
+		root = &subnet.Subnet{
+			Address: address,
+			MaskLen: uint32(maskLength),
+		}
+	} else if _, err := os.Stat(planFile); err != nil {
+		fmt.Println("Usage: subnets [<IP address> <mask length>]")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Println("       " + commands[name].usage)
+		}
 		os.Exit(1)
 	}
-
 	if os.Getenv("HELP_DEBUG") != "" {
 		f, err := tea.LogToFile("tmp/debug.log", "help")
 		if err != nil {
@@ -221,20 +403,43 @@ func main() {
 		height:   h,
 		width:    w,
 	}
-	m.subnet = &subnet.Subnet{
-		Address: subnet.InetAton(ipAddr),
-		MaskLen: uint32(maskLength),
+	m.store, m.workspace, err = openPlan(planFile, root)
+	if err != nil {
+		fmt.Printf("Error opening %s: %v\n", planFile, err)
+		os.Exit(1)
 	}
 
-	nodes := []tree.Node{toNodeTree(m.subnet)}
-	m.tree = tree.New(nodes)
+	m.tree = tree.New(nil)
+	m.rows()
 
 	if _, err := tea.NewProgram(m).Run(); err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
 }
-func toNodeTree(n *subnet.Subnet) tree.Node {
+
+// toNodeTree converts a subnet tree into the rows of the tree view.
+func toNodeTree(root *subnet.Subnet) tree.Node {
+	// Post-order visits both halves of a subnet right before the subnet
+	// itself, so their rows are the last ones pushed on the stack.
+	var stack []tree.Node
+	for n := range subnet.Nodes(root, subnet.PostOrder) {
+		halves := 0
+		if n.Left != nil {
+			halves++
+		}
+		if n.Right != nil {
+			halves++
+		}
+		node := toNode(n)
+		node.Children = append([]tree.Node{}, stack[len(stack)-halves:]...)
+		stack = append(stack[:len(stack)-halves], node)
+	}
+	return stack[0]
+}
+
+// toNode returns the row for n without its children.
+func toNode(n *subnet.Subnet) tree.Node {
 	// Convert the subnet's address and mask length to a string representation.
 	// This will be the node's value.
 	value := fmt.Sprintf("%s/%s", subnet.InetNtoa(n.Address), fmt.Sprint(n.MaskLen))
@@ -247,27 +452,21 @@ func toNodeTree(n *subnet.Subnet) tree.Node {
 	netmask := subnet.SubnetNetmask(n.MaskLen)
 	columnKeyMask := subnet.InetNtoa(netmask)
 	columnKeyAddrs := subnet.InetNtoa(s+1) + " - " + subnet.InetNtoa(lastAddress)
-	columnKeyUseable := subnet.InetNtoa(s+1) + " - " + subnet.InetNtoa(lastAddress-1)
+	firstUsable, lastUsable := subnet.UsableRange(s, n.MaskLen)
+	columnKeyUseable := subnet.InetNtoa(firstUsable) + " - " + subnet.InetNtoa(lastUsable)
 	columnKeyHosts := fmt.Sprint(subnet.SubnetAddresses(n.MaskLen))
-	desc := fmt.Sprintf("| Netmask: %s | Range of addressess %s | Useable IPs %s | Hosts %s |", columnKeyMask, columnKeyAddrs, columnKeyUseable, columnKeyHosts)
-
-	// Initialize the Node with the value and description.
-	node := tree.Node{
-		Value: value,
-		Desc:  desc,
+	columnKeyScope := subnet.Classify(n.Address, n.MaskLen).String()
+	desc := fmt.Sprintf("| Netmask: %s | Range of addressess %s | Useable IPs %s | Hosts %s | %s |", columnKeyMask, columnKeyAddrs, columnKeyUseable, columnKeyHosts, columnKeyScope)
+	if used := describeUsage(n); used != "" {
+		desc += " " + used + " |"
 	}
 
-	// Recursively convert the Subnet's children to Nodes and add them to the current Node's children.
-	children := []tree.Node{}
-	if n.Left != nil {
-		children = append(children, toNodeTree(n.Left))
-	}
-	if n.Right != nil {
-		children = append(children, toNodeTree(n.Right))
+	// Initialize the Node with the value and description.
+	return tree.Node{
+		Value:       value,
+		Desc:        desc,
+		Utilization: utilization(n),
 	}
-	node.Children = children
-
-	return node
 }
 
 func (m model) helpView() string {
@@ -276,6 +475,12 @@ func (m model) helpView() string {
 
 func (m model) ShortHelp() []key.Binding {
 	sh := m.tree.ShortHelp()
+	if m.fileChanged {
+		return []key.Binding{m.KeyMap.Reload, m.KeyMap.Merge, m.KeyMap.Ignore}
+	}
+	if m.hostsOf != "" {
+		return []key.Binding{m.KeyMap.AssignHost, m.KeyMap.Hosts}
+	}
 	kb := []key.Binding{
 		m.KeyMap.Divide,
 		m.KeyMap.Join,
@@ -292,6 +497,16 @@ func (m model) FullHelp() [][]key.Binding {
 	kb := [][]key.Binding{{
 		m.KeyMap.Divide,
 		m.KeyMap.Join,
+		m.KeyMap.Compact,
+		m.KeyMap.AddRoot,
+		m.KeyMap.RemoveRoot,
+		m.KeyMap.Save,
+		m.KeyMap.Export,
+		m.KeyMap.Free,
+		m.KeyMap.SetUsed,
+		m.KeyMap.SortUsage,
+		m.KeyMap.Hosts,
+		m.KeyMap.Filter,
 		m.KeyMap.Quit,
 
 		m.KeyMap.CloseFullHelp,
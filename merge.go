@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// runMerge merges the changes two plans made to a common base. With no -o
+// the result overwrites ours, which is what git expects of a merge driver.
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	output := fs.String("o", "", "write the merged plan to this file instead of ours")
+	path := fs.String("path", "", "name of the merged file, used to choose the plan format")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 3 {
+		return errUsage
+	}
+	if *output == "" {
+		*output = fs.Arg(1)
+	}
+	if *path == "" {
+		*path = *output
+	}
+
+	var plans [3]*subnet.Workspace
+	for i, file := range fs.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		// git passes an empty base when the sides have no common ancestor.
+		if i == 0 && len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if plans[i], err = subnet.UnmarshalWorkspace(data, *path); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	merged, conflicts := subnet.MergeWorkspaces(plans[0], plans[1], plans[2])
+	data, err := subnet.MarshalWorkspace(merged, *path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		for _, c := range conflicts {
+			fmt.Println("CONFLICT", c)
+		}
+		return fmt.Errorf("%d conflicts in %s, kept ours", len(conflicts), *path)
+	}
+	return nil
+}
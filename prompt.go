@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startPrompt shows a one line prompt above the help. submit is called with
+// the entered text when enter is pressed; esc cancels.
+func (m *model) startPrompt(prompt, placeholder string, submit func(m *model, value string)) tea.Cmd {
+	m.input = textinput.New()
+	m.input.Prompt = prompt
+	m.input.Placeholder = placeholder
+	m.submit = submit
+	return m.input.Focus()
+}
+
+// updatePrompt handles keys while a prompt is shown.
+func (m *model) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.submit = nil
+		return nil
+	case tea.KeyEnter:
+		submit := m.submit
+		m.submit = nil
+		submit(m, m.input.Value())
+		return nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return cmd
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// runQuery prints the leaves of a plan matching a query expression.
+func runQuery(args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	ws, err := subnet.LoadWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	q, err := subnet.ParseQuery(args[1])
+	if err != nil {
+		return err
+	}
+	for _, n := range subnet.Select(ws, q) {
+		fmt.Println(describeSubnet(n))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// runRange2CIDR prints the CIDR blocks covering an address range or, with
+// --into, divides a plan down to them and labels the new leaves.
+func runRange2CIDR(args []string) error {
+	fs := flag.NewFlagSet("range2cidr", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	into := fs.String("into", "", "divide this plan down to the blocks and save it")
+	fields := fs.String("label", "", "space separated labels and key=value metadata for the new leaves")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	r, err := subnet.ParseRange(fs.Arg(0) + "-" + fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	if *into == "" {
+		for _, n := range subnet.RangeToCIDRs(r) {
+			fmt.Println(n.CIDR())
+		}
+		return nil
+	}
+	labels, metadata, err := subnet.ParseFields(*fields)
+	if err != nil {
+		return err
+	}
+	var leaves []*subnet.Subnet
+	err = updatePlan(*into, "", func(ws *subnet.Workspace) error {
+		var err error
+		leaves, err = subnet.ImportRange(ws, r, labels, metadata)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, leaf := range leaves {
+		fmt.Println(describeSubnet(leaf))
+	}
+	return nil
+}
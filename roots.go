@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rochana-atapattu/subnets/internal/store"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// openPlan opens the TUI's plan file. If root is not nil and not yet a root
+// of the plan it is added, and a missing file is started with it.
+func openPlan(file string, root *subnet.Subnet) (*store.Store, *subnet.Workspace, error) {
+	st, err := store.Open(file)
+	if errors.Is(err, fs.ErrNotExist) && root != nil {
+		ws, err := subnet.NewWorkspace(root)
+		if err != nil {
+			return nil, nil, err
+		}
+		st, err := store.New(file, ws)
+		return st, ws, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ws, _ := st.Snapshot()
+	if root != nil {
+		if r := ws.Root(root.Address, root.MaskLen); r == nil || r.MaskLen != root.MaskLen {
+			if err := ws.AddTree(root); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return st, ws, nil
+}
+
+// selected returns the subnet at the tree's cursor.
+func (m model) selected() *subnet.Subnet {
+	node, ok := m.tree.GetNodeAtCurrentCursor()
+	if !ok {
+		return nil
+	}
+	address, maskLen, err := subnet.ParseCIDR(node.Value)
+	if err != nil {
+		return nil
+	}
+	return m.workspace.Find(address, maskLen)
+}
+
+// startAddRoot shows the prompt for a new root prefix.
+func (m *model) startAddRoot() tea.Cmd {
+	return m.startPrompt("New root: ", "172.16.0.0/12", (*model).addRoot)
+}
+
+// addRoot adds the root prefix entered at the prompt.
+func (m *model) addRoot(value string) {
+	address, maskLen, err := subnet.ParseCIDR(value)
+	if err == nil {
+		_, err = m.workspace.Add(address, maskLen)
+	}
+	if err != nil {
+		m.status = fmt.Sprint("Error adding root: ", err)
+		return
+	}
+	m.status = "Added root " + subnet.FormatCIDR(subnet.NetworkAddress(address, maskLen), maskLen)
+}
+
+// removeRoot removes the selected root, asking to press the key again first
+// if anything is planned inside it. The last root is kept, as the TUI has
+// nothing to show without one.
+func (m *model) removeRoot() {
+	n := m.selected()
+	if n == nil || n.Parent != nil {
+		m.status = "Select a root to remove it"
+		return
+	}
+	if len(m.workspace.Roots) == 1 {
+		m.status = "Cannot remove the last root; add another first"
+		return
+	}
+	if !n.IsFree() && m.removing != n.CIDR() {
+		m.removing = n.CIDR()
+		m.status = fmt.Sprintf("%s is divided or labelled; press %s again to remove it", n.CIDR(), m.KeyMap.RemoveRoot.Help().Key)
+		return
+	}
+	m.removing = ""
+	if err := m.workspace.Remove(n.Address, n.MaskLen); err != nil {
+		m.status = fmt.Sprint("Error removing root: ", err)
+		return
+	}
+	m.status = "Removed root " + n.CIDR()
+}
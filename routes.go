@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rochana-atapattu/subnets/internal/routes"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// routeParsers reads each format accepted by "subnets routes --format".
+var routeParsers = map[string]func(io.Reader) ([]routes.Route, error){
+	"ip":      routes.ParseIPRoute,
+	"netstat": routes.ParseNetstat,
+	"show":    routes.ParseShowRoute,
+}
+
+// runRoutes builds a plan from saved routing tables or, with --check, reports
+// the routes missing from or conflicting with an existing plan.
+func runRoutes(args []string) error {
+	fs := flag.NewFlagSet("routes", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "", "input format: ip, netstat or show")
+	check := fs.String("check", "", "plan to check the routes against")
+	output := fs.String("o", "", "plan file to write, defaults to standard output")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	parse, ok := routeParsers[*format]
+	if !ok || fs.NArg() == 0 || (*check != "" && *output != "") {
+		return errUsage
+	}
+
+	var table []routes.Route
+	for _, file := range fs.Args() {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		r, err := parse(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		table = append(table, r...)
+	}
+
+	if *check != "" {
+		ws, err := subnet.LoadWorkspace(*check)
+		if err != nil {
+			return err
+		}
+		findings := routes.Check(ws, table)
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+		if len(findings) > 0 {
+			return fmt.Errorf("%d of %d routes missing from or conflicting with %s", len(findings), len(table), *check)
+		}
+		return nil
+	}
+
+	ws, err := routes.Build(table)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return subnet.WriteFlatWorkspace(os.Stdout, ws)
+	}
+	return subnet.SaveWorkspace(ws, *output)
+}
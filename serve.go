@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/rochana-atapattu/subnets/internal/server"
+	"github.com/rochana-atapattu/subnets/internal/store"
+)
+
+// runServe serves a plan file as editable web pages.
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	file := flags.String("file", "subnets.json", "plan file to edit")
+	rootCIDRs := flags.String("root", "", "comma separated root prefixes for a new plan if the file does not exist")
+	addr := flags.String("addr", ":8080", "address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	st, err := store.Open(*file)
+	if errors.Is(err, fs.ErrNotExist) && *rootCIDRs != "" {
+		ws, werr := newWorkspace(*rootCIDRs)
+		if werr != nil {
+			return werr
+		}
+		st, err = store.New(*file, ws)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Serving %s on %s\n", *file, *addr)
+	return server.New(st).Handler().Start(*addr)
+}
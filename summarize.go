@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// dash matches the dash of a range written with spaces around it.
+var dash = regexp.MustCompile(`\s*[-–]\s*`)
+
+// runSummarize reads prefixes, addresses and ranges from the given files, or
+// stdin, and prints the fewest CIDR blocks covering exactly those addresses.
+func runSummarize(args []string) error {
+	var ranges []subnet.Range
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	for _, file := range args {
+		var r io.Reader = os.Stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		rs, err := readRanges(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		ranges = append(ranges, rs...)
+	}
+	for _, n := range subnet.Summarize(ranges) {
+		fmt.Println(n.CIDR())
+	}
+	return nil
+}
+
+// readRanges parses whitespace or comma separated prefixes, addresses and
+// ranges. Text after a "#" is a comment.
+func readRanges(r io.Reader) ([]subnet.Range, error) {
+	var ranges []subnet.Range
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = dash.ReplaceAllString(text, "-")
+		for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			rng, err := subnet.ParseRange(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			ranges = append(ranges, rng)
+		}
+	}
+	return ranges, scanner.Err()
+}
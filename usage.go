@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+	"github.com/rochana-atapattu/subnets/internal/usage"
+)
+
+// usageParsers reads each format accepted by "subnets usage --format".
+var usageParsers = map[string]func(io.Reader) ([]usage.Observation, error){
+	"neigh": usage.ParseNeigh,
+	"arp":   usage.ParseARP,
+	"dhcpd": usage.ParseDhcpdLeases,
+	"kea":   usage.ParseKeaLeases,
+}
+
+// runUsage records the addresses found in saved neighbour tables or lease
+// files as hosts of a plan and updates its used counts.
+func runUsage(args []string) error {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "", "input format: neigh, arp, dhcpd or kea")
+	output := fs.String("o", "", "plan file to write, defaults to the input plan")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	parse, ok := usageParsers[*format]
+	if !ok || fs.NArg() < 2 {
+		return errUsage
+	}
+
+	var observations []usage.Observation
+	for _, file := range fs.Args()[1:] {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		o, err := parse(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		observations = append(observations, o...)
+	}
+	var skipped []usage.Skipped
+	err := updatePlan(fs.Arg(0), *output, func(ws *subnet.Workspace) error {
+		var err error
+		skipped, err = usage.Apply(ws, observations)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recorded %d addresses\n", len(observations)-len(skipped))
+	for _, s := range skipped {
+		fmt.Println("Skipped", s)
+	}
+	return nil
+}
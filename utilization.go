@@ -0,0 +1,73 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+	"github.com/rochana-atapattu/subnets/internal/tree"
+)
+
+// startSetUsed shows the prompt for the number of addresses in use in the
+// selected leaf.
+func (m *model) startSetUsed() tea.Cmd {
+	n := m.selected()
+	if n == nil || n.Left != nil || n.Right != nil {
+		m.status = "Select a leaf to set its usage"
+		return nil
+	}
+	return m.startPrompt(fmt.Sprintf("Addresses used in %s: ", n.CIDR()), fmt.Sprint(n.Size()), func(m *model, value string) {
+		used, err := strconv.ParseUint(value, 10, 64)
+		if err == nil {
+			err = n.SetUsed(used)
+		}
+		if err != nil {
+			m.status = fmt.Sprint("Error setting usage: ", err)
+			return
+		}
+		m.status = fmt.Sprintf("%s: %s", n.CIDR(), describeUsage(n))
+	})
+}
+
+// describeUsage describes how many addresses of n are in use, or returns "" if no
+// leaf under n records it.
+func describeUsage(n *subnet.Subnet) string {
+	used, ok := n.Used()
+	if !ok {
+		return ""
+	}
+	util, _ := n.Utilization()
+	return fmt.Sprintf("Used %d of %d (%.0f%%)", used, n.Size(), 100*util)
+}
+
+// utilization returns the utilization of n for the tree's row colour, or -1
+// if it is not tracked.
+func utilization(n *subnet.Subnet) float64 {
+	util, ok := n.Utilization()
+	if !ok {
+		return -1
+	}
+	return util
+}
+
+// utilizationRows lists every node with tracked usage, fullest first,
+// keeping only the leaves matching the filter if one is set.
+func (m *model) utilizationRows() []tree.Node {
+	var nodes []*subnet.Subnet
+	m.workspace.Walk(func(n *subnet.Subnet) {
+		if _, ok := n.Used(); ok && (m.filter == nil || m.filter.Matches(n)) {
+			nodes = append(nodes, n)
+		}
+	})
+	slices.SortStableFunc(nodes, func(a, b *subnet.Subnet) int {
+		return cmp.Compare(utilization(b), utilization(a))
+	})
+	rows := make([]tree.Node, len(nodes))
+	for i, n := range nodes {
+		rows[i] = toNode(n)
+	}
+	return rows
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rochana-atapattu/subnets/internal/store"
+	"github.com/rochana-atapattu/subnets/internal/subnet"
+)
+
+// watchInterval is how often the TUI checks whether the plan file changed.
+const watchInterval = time.Second
+
+var styleStatus = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.AdaptiveColor{Light: "#af8700", Dark: "#ffd75f"})
+
+// checkFileMsg asks the model to check the plan file for changes.
+type checkFileMsg struct{}
+
+// fileChangedMsg reports that another process saved the plan file, with the
+// hash of the contents it saved.
+type fileChangedMsg struct {
+	sum [sha256.Size]byte
+}
+
+// watchFile schedules the next check of the plan file.
+func watchFile() tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		return checkFileMsg{}
+	})
+}
+
+// checkFile reports a fileChangedMsg if the store's file changed on disk.
+func checkFile(st *store.Store) tea.Cmd {
+	return func() tea.Msg {
+		if changed, err := st.Changed(); err != nil || !changed {
+			return nil
+		}
+		data, err := os.ReadFile(st.File())
+		if err != nil {
+			return nil
+		}
+		return fileChangedMsg{sha256.Sum256(data)}
+	}
+}
+
+// notifyChanged shows the prompt to reload or merge, unless it is already
+// shown or the user ignored the same change.
+func (m *model) notifyChanged(sum [sha256.Size]byte) {
+	if m.fileChanged || m.ignored != nil && *m.ignored == sum {
+		return
+	}
+	m.promptChanged()
+}
+
+// promptChanged shows the prompt to reload or merge.
+func (m *model) promptChanged() {
+	m.fileChanged = true
+	m.status = fmt.Sprintf("%s was changed by another process: r reload, m merge, i ignore", m.store.File())
+}
+
+// ignoreChanged hides the prompt to reload or merge until the file changes
+// again.
+func (m *model) ignoreChanged() {
+	m.fileChanged = false
+	m.status = ""
+	if data, err := os.ReadFile(m.store.File()); err == nil {
+		sum := sha256.Sum256(data)
+		m.ignored = &sum
+	}
+}
+
+// synced clears the change prompt after the model caught up with the file.
+func (m *model) synced(status string) {
+	m.ignored = nil
+	m.fileChanged = false
+	m.status = status
+}
+
+// savePlan saves the model's workspace, prompting to reload or merge if another
+// process saved the file first.
+func (m *model) savePlan() {
+	if _, err := m.store.Replace(0, m.workspace); err != nil {
+		if errors.Is(err, store.ErrModified) {
+			m.promptChanged()
+			return
+		}
+		m.status = fmt.Sprint("Error saving subnet tree: ", err)
+		return
+	}
+	m.synced("Saved " + m.store.File())
+}
+
+// reloadPlan replaces the model's workspace with the saved plan.
+func (m *model) reloadPlan() {
+	ws, _, err := m.store.Reload()
+	if err != nil {
+		m.status = fmt.Sprint("Error loading subnet tree: ", err)
+		return
+	}
+	m.workspace = ws
+	m.synced("Loaded " + m.store.File())
+}
+
+// mergePlan loads the saved plan and merges in the model's unsaved changes.
+// Where both changed the same subnet the model's version is kept. The result
+// is not saved.
+func (m *model) mergePlan() {
+	base, _ := m.store.Snapshot()
+	theirs, _, err := m.store.Reload()
+	if err != nil {
+		m.status = fmt.Sprint("Error loading subnet tree: ", err)
+		return
+	}
+	merged, conflicts := subnet.MergeWorkspaces(base, m.workspace, theirs)
+	m.workspace = merged
+	if len(conflicts) == 0 {
+		m.synced("Merged changes from " + m.store.File())
+		return
+	}
+	status := fmt.Sprintf("Merged with %d conflicts, kept yours:", len(conflicts))
+	for _, c := range conflicts {
+		status += " " + c.String() + ";"
+	}
+	m.synced(status)
+}